@@ -57,3 +57,34 @@ func OutputFromContext(ctx context.Context) OutputType {
 	}
 	return OutputTypeConsole
 }
+
+const GroupedOutputKey = "grouped-output"
+
+func ContextWithGroupedOutput(ctx context.Context) context.Context {
+	return context.WithValue(ctx, GroupedOutputKey, true)
+}
+
+// GroupedOutputFromContext returns whether the output of a node should be grouped and only written once the node is
+// done, instead of being streamed line by line
+func GroupedOutputFromContext(ctx context.Context) bool {
+	if v := ctx.Value(GroupedOutputKey); v != nil {
+		return v.(bool)
+	}
+
+	return false
+}
+
+const TerminalUIKey = "terminal-ui"
+
+func ContextWithTerminalUI(ctx context.Context) context.Context {
+	return context.WithValue(ctx, TerminalUIKey, true)
+}
+
+// TerminalUIFromContext returns whether the progress of graph runs can be shown in an interactive terminal UI
+func TerminalUIFromContext(ctx context.Context) bool {
+	if v := ctx.Value(TerminalUIKey); v != nil {
+		return v.(bool)
+	}
+
+	return false
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// LineWriter calls a function for every complete line that is written to it. This is used to stream the output of
+// long-running commands line by line instead of waiting for the command to finish
+type LineWriter struct {
+	fn     func(line string)
+	buffer []byte
+	mu     sync.Mutex
+}
+
+func NewLineWriter(fn func(line string)) *LineWriter {
+	return &LineWriter{fn: fn}
+}
+
+func (l *LineWriter) Write(p []byte) (n int, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buffer = append(l.buffer, p...)
+	for {
+		i := bytes.IndexByte(l.buffer, '\n')
+		if i < 0 {
+			break
+		}
+		l.fn(strings.TrimRight(string(l.buffer[:i]), "\r"))
+		l.buffer = l.buffer[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush passes any remaining data that is not terminated by a newline to the line function
+func (l *LineWriter) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.buffer) > 0 {
+		l.fn(strings.TrimRight(string(l.buffer), "\r"))
+		l.buffer = nil
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := NewLineWriter(func(line string) {
+		lines = append(lines, line)
+	})
+
+	_, err := w.Write([]byte("first line\nsecond "))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first line"}, lines)
+
+	_, err = w.Write([]byte("line\r\n\nthird"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first line", "second line", ""}, lines)
+
+	require.NoError(t, w.Flush())
+	assert.Equal(t, []string{"first line", "second line", "", "third"}, lines)
+}
@@ -1,9 +1,18 @@
 package cmd
 
 import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/elliotchance/pie/v2"
 	"github.com/mach-composer/mach-composer-cli/internal/batcher"
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
 	"github.com/mach-composer/mach-composer-cli/internal/hash"
+	"github.com/mach-composer/mach-composer-cli/internal/snapshot"
+	"github.com/mattn/go-isatty"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
@@ -15,9 +24,12 @@ var applyFlags struct {
 	forceInit             bool
 	autoApprove           bool
 	destroy               bool
+	destroyRemoved        bool
 	components            []string
 	numWorkers            int
 	ignoreChangeDetection bool
+	approvalFile          string
+	approvalTimeout       time.Duration
 }
 
 var applyCmd = &cobra.Command{
@@ -34,11 +46,16 @@ var applyCmd = &cobra.Command{
 
 func init() {
 	registerCommonFlags(applyCmd)
+	registerRunnerFlags(applyCmd)
 	applyCmd.Flags().BoolVarP(&applyFlags.forceInit, "force-init", "", false, "Force terraform initialization. By default mach-composer will reuse existing terraform resources")
 	applyCmd.Flags().BoolVarP(&applyFlags.autoApprove, "auto-approve", "", false, "Suppress a terraform init for improved speed (not recommended for production usage)")
 	applyCmd.Flags().BoolVarP(&applyFlags.destroy, "destroy", "", false, "Destroy option is a convenient way to destroy all remote objects managed by this mach config")
+	applyCmd.Flags().BoolVarP(&applyFlags.destroyRemoved, "destroy-removed", "", false, "Only destroy the resources of sites and components that were applied before, but are removed from the mach config")
+	applyCmd.MarkFlagsMutuallyExclusive("destroy", "destroy-removed")
 	applyCmd.Flags().StringArrayVarP(&applyFlags.components, "component", "c", nil, "")
 	applyCmd.Flags().BoolVarP(&applyFlags.ignoreChangeDetection, "ignore-change-detection", "", false, "Ignore change detection to run even if the components are considered up to date")
+	applyCmd.Flags().StringVarP(&applyFlags.approvalFile, "approval-file", "", "", "When not running in a terminal, wait for the approval tokens of sites that require approval to be added to this file")
+	applyCmd.Flags().DurationVarP(&applyFlags.approvalTimeout, "approval-timeout", "", time.Hour, "How long to wait for approval tokens to be added to the approval file")
 }
 
 func applyFunc(cmd *cobra.Command, _ []string) error {
@@ -55,10 +72,8 @@ func applyFunc(cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
-	// Note that we do this in multiple passes to minimize ending up with
-	// half broken runs. We could in the future also run some parts in parallel
-
-	err = generator.Write(ctx, cfg, dg, nil)
+	snapshots := snapshot.Factory(cfg)
+	removed, err := snapshots.Removed(dg)
 	if err != nil {
 		return err
 	}
@@ -68,11 +83,88 @@ func applyFunc(cmd *cobra.Command, _ []string) error {
 		hash.Factory(cfg),
 		commonFlags.workers,
 	)
+	r.UseSnapshotStore(snapshots)
 
-	return r.TerraformApply(ctx, dg, &runner.ApplyOptions{
+	if applyFlags.destroyRemoved {
+		return destroyRemoved(ctx, cfg, r, removed)
+	}
+
+	for _, s := range removed {
+		log.Warn().Msgf("%s is removed from the config, but its resources still exist. Run `mach-composer apply "+
+			"--destroy-removed` to destroy them", s.Identifier)
+	}
+
+	// Note that we do this in multiple passes to minimize ending up with
+	// half broken runs. We could in the future also run some parts in parallel
+
+	err = generator.Write(ctx, cfg, dg, nil)
+	if err != nil {
+		return err
+	}
+
+	opts := &runner.ApplyOptions{
 		ForceInit:             applyFlags.forceInit,
 		Destroy:               applyFlags.destroy,
 		AutoApprove:           applyFlags.autoApprove,
 		IgnoreChangeDetection: applyFlags.ignoreChangeDetection,
+	}
+
+	requiresApproval := pie.Any(cfg.Sites, func(s config.SiteConfig) bool {
+		return s.Approval == config.ApprovalRequired
 	})
+	// Changes checked against policies are planned up front, so terraform does not ask for confirmation when applying
+	// them
+	requiresConfirmation := len(cfg.MachComposer.Policies) > 0 && !applyFlags.autoApprove
+	if requiresApproval || requiresConfirmation {
+		opts.Approver = newApprover(ctx)
+	}
+
+	// Terraform or the approval of changes ask for confirmation unless auto approve is set, which is not possible while
+	// the UI is shown
+	interactive := applyFlags.autoApprove && !requiresApproval
+	return runGraph(ctx, cfg, r, interactive, func(ctx context.Context) error {
+		return r.TerraformApply(ctx, dg, opts)
+	})
+}
+
+// destroyRemoved destroys the resources of the removed nodes, in reverse dependency order
+func destroyRemoved(ctx context.Context, cfg *config.MachConfig, r *runner.GraphRunner, removed []*snapshot.Snapshot) error {
+	if len(removed) == 0 {
+		log.Info().Msg("No removed sites or components to destroy")
+		return nil
+	}
+
+	for _, s := range removed {
+		log.Info().Msgf("Destroying removed %s %s", s.Type, s.Identifier)
+	}
+
+	requiresApproval := pie.Any(removed, func(s *snapshot.Snapshot) bool {
+		return s.Node(cfg).Approval == config.ApprovalRequired
+	})
+
+	// Destroying is always planned first, so the plan is confirmed unless auto approve is set
+	opts := &runner.ApplyOptions{
+		Destroy:     true,
+		AutoApprove: applyFlags.autoApprove,
+	}
+	if requiresApproval || !applyFlags.autoApprove {
+		opts.Approver = newApprover(ctx)
+	}
+
+	interactive := applyFlags.autoApprove && !requiresApproval
+	return runGraph(ctx, cfg, r, interactive, func(ctx context.Context) error {
+		return r.TerraformDestroyRemoved(ctx, cfg, removed, opts)
+	})
+}
+
+// newApprover returns the approver of changes, which asks for approval in the terminal or else waits for approval
+// tokens
+func newApprover(ctx context.Context) runner.Approver {
+	if isatty.IsTerminal(os.Stdin.Fd()) && isatty.IsTerminal(os.Stdout.Fd()) && !cli.GithubCIFromContext(ctx) {
+		return runner.NewTerminalApprover()
+	}
+	return &runner.TokenApprover{
+		File:    applyFlags.approvalFile,
+		Timeout: applyFlags.approvalTimeout,
+	}
 }
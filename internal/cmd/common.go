@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/mach-composer/mach-composer-cli/internal/cloud"
@@ -13,6 +14,10 @@ import (
 
 	"github.com/mach-composer/mach-composer-cli/internal/cli"
 	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/runner"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/mach-composer/mach-composer-cli/internal/tui"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
 )
 
 type CommonFlags struct {
@@ -105,5 +110,107 @@ func loadConfig(cmd *cobra.Command, resolveVars bool) *config.MachConfig {
 		}
 	}
 
+	if tf := cfg.MachComposer.Terraform; tf.HasBinary() {
+		binary, err := terraform.ResolveBinary(cmd.Context(), terraform.BinaryOptions{
+			Binary:  tf.Binary,
+			Version: tf.Version,
+			Install: tf.Install,
+		})
+		if err != nil {
+			cli.PrintExitError("An error occurred while resolving the terraform binary", err.Error())
+		}
+		cmd.SetContext(utils.ContextWithTerraformBinary(cmd.Context(), binary))
+	}
+
 	return cfg
 }
+
+const defaultProviderCacheDir = ".mach-composer/providers"
+
+var runnerFlags struct {
+	eventsFile       string
+	eventsWebhook    string
+	providerCacheDir string
+	noProviderCache  bool
+}
+
+// registerRunnerFlags registers the flags of commands that run terraform on the deployment graph
+func registerRunnerFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&runnerFlags.eventsFile, "events-file", "", "",
+		"Write the progress events of the run to this file as json lines")
+	cmd.Flags().StringVarP(&runnerFlags.eventsWebhook, "events-webhook", "", "",
+		"Post the progress events of the run as json to this URL")
+	cmd.Flags().StringVarP(&runnerFlags.providerCacheDir, "provider-cache-dir", "", "",
+		"Directory in which terraform providers are cached and shared between components. Defaults to the "+
+			"MC_PROVIDER_CACHE_DIR or TF_PLUGIN_CACHE_DIR environment variable, or "+defaultProviderCacheDir)
+	cmd.Flags().BoolVarP(&runnerFlags.noProviderCache, "no-provider-cache", "", false,
+		"Do not share terraform providers between components")
+}
+
+// providerCacheDir returns the directory of the shared provider cache
+func providerCacheDir() string {
+	for _, dir := range []string{
+		runnerFlags.providerCacheDir,
+		os.Getenv("MC_PROVIDER_CACHE_DIR"),
+		os.Getenv("TF_PLUGIN_CACHE_DIR"),
+	} {
+		if dir != "" {
+			return dir
+		}
+	}
+	return defaultProviderCacheDir
+}
+
+// runGraph calls fn with the configured event sinks subscribed to the runner, and the lock directory, retry policy and
+// shared provider cache set. If interactive is set the progress is shown in the interactive terminal UI when it is
+// enabled; commands for which terraform might need to prompt for input should not be interactive.
+func runGraph(
+	ctx context.Context, cfg *config.MachConfig, r *runner.GraphRunner, interactive bool,
+	fn func(ctx context.Context) error,
+) error {
+	if runnerFlags.eventsFile != "" {
+		f, err := os.Create(runnerFlags.eventsFile)
+		if err != nil {
+			return fmt.Errorf("failed to create events file: %w", err)
+		}
+		defer f.Close()
+		r.Subscribe(runner.NewJSONLinesSubscriber(f))
+	}
+
+	if runnerFlags.eventsWebhook != "" {
+		webhook := runner.NewWebhookSubscriber(runnerFlags.eventsWebhook)
+		defer webhook.Close()
+		r.Subscribe(webhook)
+	}
+
+	if lockDir := cfg.MachComposer.Terraform.LockDir; lockDir != "" {
+		if !filepath.IsAbs(lockDir) {
+			lockDir = filepath.Join(filepath.Dir(commonFlags.configFile), lockDir)
+		}
+		r.UseLockDir(lockDir)
+	}
+
+	if retries := cfg.MachComposer.Terraform.Retries; retries.MaxAttempts > 1 {
+		policy, err := runner.NewRetryPolicy(retries)
+		if err != nil {
+			return err
+		}
+		r.UseRetryPolicy(policy)
+	}
+
+	if !runnerFlags.noProviderCache {
+		cache, err := runner.NewProviderCache(providerCacheDir())
+		if err != nil {
+			return err
+		}
+		r.UseProviderCache(cache)
+	}
+
+	if !interactive || !cli.TerminalUIFromContext(ctx) {
+		return fn(ctx)
+	}
+
+	ui := tui.New()
+	r.Subscribe(ui)
+	return ui.Run(ctx, fn)
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
+	"github.com/mach-composer/mach-composer-cli/internal/formatter"
+)
+
+var fmtFlags struct {
+	check          bool
+	sortComponents bool
+}
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt [file...]",
+	Short: "Format config files.",
+	Long: "Format config files, keeping comments. The keys of known sections are put in a consistent order and " +
+		"everything is indented with two spaces. Referenced files are not inlined. Formats main.yml when no files " +
+		"are given.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return fmtFunc(args)
+	},
+}
+
+func init() {
+	fmtCmd.Flags().BoolVarP(&fmtFlags.check, "check", "", false,
+		"Only check whether the files are formatted, without writing them. Exits with an error when any file is "+
+			"not formatted")
+	fmtCmd.Flags().BoolVarP(&fmtFlags.sortComponents, "sort-components", "", false,
+		"Sort the components and the components of every site by name")
+}
+
+func fmtFunc(files []string) error {
+	if len(files) == 0 {
+		files = []string{"main.yml"}
+	}
+
+	opts := &formatter.Options{SortComponents: fmtFlags.sortComponents}
+
+	var unformatted []error
+	for _, filename := range files {
+		changed, err := formatter.FormatFile(filename, opts, fmtFlags.check)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case !changed:
+			log.Debug().Msgf("%s is formatted", filename)
+		case fmtFlags.check:
+			unformatted = append(unformatted, fmt.Errorf("%s is not formatted", filename))
+		default:
+			log.Info().Msgf("Formatted %s", filename)
+		}
+	}
+
+	if len(unformatted) > 0 {
+		return cli.NewGroupedError("files are not formatted, run `mach-composer fmt`", unformatted)
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"os"
+
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
 	"github.com/spf13/cobra"
 
@@ -18,8 +20,24 @@ var generateCmd = &cobra.Command{
 	},
 }
 
+var generateFlags struct {
+	printTemplateContext bool
+	prune                bool
+	check                bool
+}
+
 func init() {
 	registerCommonFlags(generateCmd)
+	generateCmd.Flags().BoolVarP(&generateFlags.printTemplateContext, "print-template-context", "", false,
+		"Print the context of every template as json instead of writing the files. Useful when writing custom templates")
+	generateCmd.Flags().BoolVarP(&generateFlags.prune, "prune", "", false,
+		"Remove the directories of sites and components that are not in the config anymore. Directories that still "+
+			"hold local terraform state are kept")
+	generateCmd.Flags().BoolVarP(&generateFlags.check, "check", "", false,
+		"Only check whether the generated files are up to date, without writing them. Exits with an error when any "+
+			"file would change")
+	generateCmd.MarkFlagsMutuallyExclusive("check", "print-template-context")
+	generateCmd.MarkFlagsMutuallyExclusive("check", "prune")
 }
 
 func generateFunc(cmd *cobra.Command) error {
@@ -31,5 +49,12 @@ func generateFunc(cmd *cobra.Command) error {
 		return err
 	}
 
-	return generator.Write(cmd.Context(), cfg, gd, nil)
+	opts := &generator.GenerateOptions{
+		Prune: generateFlags.prune,
+		Check: generateFlags.check,
+	}
+	if generateFlags.printTemplateContext {
+		opts.TemplateContext = os.Stdout
+	}
+	return generator.Write(cmd.Context(), cfg, gd, opts)
 }
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"github.com/mach-composer/mach-composer-cli/internal/batcher"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
 	"github.com/mach-composer/mach-composer-cli/internal/hash"
@@ -28,6 +29,7 @@ var initCmd = &cobra.Command{
 
 func init() {
 	registerCommonFlags(initCmd)
+	registerRunnerFlags(initCmd)
 }
 
 func initFunc(cmd *cobra.Command, _ []string) error {
@@ -51,5 +53,7 @@ func initFunc(cmd *cobra.Command, _ []string) error {
 		commonFlags.workers,
 	)
 
-	return r.TerraformInit(ctx, dg)
+	return runGraph(ctx, cfg, r, true, func(ctx context.Context) error {
+		return r.TerraformInit(ctx, dg)
+	})
 }
@@ -1,7 +1,13 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
 	"github.com/mach-composer/mach-composer-cli/internal/batcher"
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
 	"github.com/mach-composer/mach-composer-cli/internal/hash"
 	"github.com/rs/zerolog/log"
@@ -16,6 +22,8 @@ var planFlags struct {
 	components            []string
 	lock                  bool
 	ignoreChangeDetection bool
+	summary               string
+	summaryFile           string
 }
 
 var planCmd = &cobra.Command{
@@ -23,6 +31,9 @@ var planCmd = &cobra.Command{
 	Short: "Plan the configuration.",
 	PreRun: func(cmd *cobra.Command, args []string) {
 		preprocessCommonFlags(cmd)
+		if planFlags.summary != "" && planFlags.summary != "markdown" && planFlags.summary != "json" {
+			cli.PrintExitError(fmt.Sprintf("Invalid summary format %s, expected markdown or json", planFlags.summary))
+		}
 	},
 
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -32,10 +43,14 @@ var planCmd = &cobra.Command{
 
 func init() {
 	registerCommonFlags(planCmd)
+	registerRunnerFlags(planCmd)
 	planCmd.Flags().BoolVarP(&planFlags.forceInit, "force-init", "", false, "Force terraform initialization. By default mach-composer will reuse existing terraform resources")
 	planCmd.Flags().StringArrayVarP(&planFlags.components, "component", "c", nil, "")
 	planCmd.Flags().BoolVarP(&planFlags.lock, "lock", "", true, "Acquire a lock on the state file before running terraform plan")
 	planCmd.Flags().BoolVarP(&planFlags.ignoreChangeDetection, "ignore-change-detection", "", false, "Ignore change detection to run even if the components are considered up to date")
+	planCmd.Flags().StringVarP(&planFlags.summary, "summary", "", "", "Write a summary of the planned changes of all components. Options: markdown, json")
+	planCmd.Flags().StringVarP(&planFlags.summaryFile, "summary-file", "", "", "File to write the summary to. Defaults to stdout")
+	_ = planCmd.RegisterFlagCompletionFunc("summary", cobra.FixedCompletions([]string{"markdown", "json"}, cobra.ShellCompDirectiveNoFileComp))
 }
 
 func planFunc(cmd *cobra.Command, _ []string) error {
@@ -63,9 +78,49 @@ func planFunc(cmd *cobra.Command, _ []string) error {
 		commonFlags.workers,
 	)
 
-	return r.TerraformPlan(ctx, dg, &runner.PlanOptions{
-		ForceInit:             planFlags.forceInit,
-		Lock:                  planFlags.lock,
-		IgnoreChangeDetection: planFlags.ignoreChangeDetection,
+	var report *runner.PlanReport
+	if planFlags.summary != "" {
+		report = runner.NewPlanReport()
+		r.Subscribe(report)
+	}
+
+	err = runGraph(ctx, cfg, r, true, func(ctx context.Context) error {
+		return r.TerraformPlan(ctx, dg, &runner.PlanOptions{
+			ForceInit:             planFlags.forceInit,
+			Lock:                  planFlags.lock,
+			IgnoreChangeDetection: planFlags.ignoreChangeDetection,
+			Report:                report,
+		})
 	})
+
+	// The summary is also written when the plan failed, so it shows which components failed
+	if report != nil {
+		if sErr := writePlanSummary(ctx, report); sErr != nil {
+			log.Error().Err(sErr).Msg("Failed to write plan summary")
+		}
+	}
+	return err
+}
+
+func writePlanSummary(ctx context.Context, report *runner.PlanReport) error {
+	var w io.Writer = os.Stdout
+	if planFlags.summaryFile != "" {
+		f, err := os.Create(planFlags.summaryFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if cli.GithubCIFromContext(ctx) {
+		if err := report.RenderGithubAnnotations(os.Stdout); err != nil {
+			return err
+		}
+	}
+
+	if planFlags.summary == "json" {
+		return report.RenderJSON(w)
+	}
+	return report.RenderMarkdown(w)
 }
@@ -0,0 +1,315 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/elliotchance/pie/v2"
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/plugins"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var pluginsFlags struct {
+	platforms []string
+	dryRun    bool
+}
+
+var pluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "Manage the plugins used by the configuration",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var pluginsLockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Update the plugin lock file with the checksums of the configured plugins",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		preprocessCommonFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return pluginsLockFunc(cmd)
+	},
+}
+
+var pluginsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the configured plugins and the installed versions",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		preprocessCommonFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return pluginsListFunc(cmd)
+	},
+}
+
+var pluginsInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Download all configured plugins, without loading the configuration",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		preprocessCommonFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return pluginsInstallFunc(cmd)
+	},
+}
+
+var pluginsVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the installed plugins against the plugin lock file",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		preprocessCommonFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return pluginsVerifyFunc(cmd)
+	},
+}
+
+var pluginsCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove installed versions of the configured plugins that are no longer used",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		preprocessCommonFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return pluginsCleanFunc(cmd)
+	},
+}
+
+var pluginsMirrorCmd = &cobra.Command{
+	Use:   "mirror <dir>",
+	Short: "Download the configured plugins into a local filesystem mirror",
+	Args:  cobra.ExactArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		preprocessCommonFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return pluginsMirrorFunc(cmd, args[0])
+	},
+}
+
+func init() {
+	registerCommonFlags(pluginsLockCmd)
+	pluginsLockCmd.Flags().StringArrayVarP(&pluginsFlags.platforms, "platform", "", nil,
+		"Platform to record checksums for, in the form os_arch. Can be repeated. Defaults to the current platform")
+
+	registerCommonFlags(pluginsMirrorCmd)
+	pluginsMirrorCmd.Flags().StringArrayVarP(&pluginsFlags.platforms, "platform", "", nil,
+		"Platform to download plugins for, in the form os_arch. Can be repeated. Defaults to the current platform")
+
+	registerCommonFlags(pluginsListCmd)
+	registerCommonFlags(pluginsInstallCmd)
+	registerCommonFlags(pluginsVerifyCmd)
+	registerCommonFlags(pluginsCleanCmd)
+	pluginsCleanCmd.Flags().BoolVarP(&pluginsFlags.dryRun, "dry-run", "", false, "Only list the versions that would be removed")
+
+	pluginsCmd.AddCommand(pluginsLockCmd)
+	pluginsCmd.AddCommand(pluginsMirrorCmd)
+	pluginsCmd.AddCommand(pluginsListCmd)
+	pluginsCmd.AddCommand(pluginsInstallCmd)
+	pluginsCmd.AddCommand(pluginsVerifyCmd)
+	pluginsCmd.AddCommand(pluginsCleanCmd)
+}
+
+func pluginsLockFunc(cmd *cobra.Command) error {
+	configs, err := config.OpenPluginConfigs(commonFlags.configFile)
+	if err != nil {
+		return err
+	}
+
+	platforms, err := parsePlatforms(pluginsFlags.platforms)
+	if err != nil {
+		return err
+	}
+
+	lock, err := plugins.LoadLockFile(config.LockFilePath(commonFlags.configFile))
+	if err != nil {
+		return err
+	}
+
+	if err := plugins.UpdateLockFile(lock, configs, platforms); err != nil {
+		return err
+	}
+
+	if err := lock.Save(); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("Updated %s", lock.Filename())
+	return nil
+}
+
+func parsePlatforms(values []string) ([]plugins.Platform, error) {
+	if len(values) == 0 {
+		return []plugins.Platform{plugins.CurrentPlatform()}, nil
+	}
+
+	var result []plugins.Platform
+	for _, value := range values {
+		p, err := plugins.ParsePlatform(value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+func pluginsListFunc(cmd *cobra.Command) error {
+	configs, err := config.OpenPluginConfigs(commonFlags.configFile)
+	if err != nil {
+		return err
+	}
+
+	lock, err := plugins.LoadLockFile(config.LockFilePath(commonFlags.configFile))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s:\n", commonFlags.configFile)
+	for _, name := range pie.Sort(pie.Keys(configs)) {
+		cfg := configs[name]
+
+		fmt.Printf(" - %s\n", name)
+		fmt.Printf("   source: %s\n", cfg.Source)
+		fmt.Printf("   version: %s\n", cfg.Version)
+
+		if cfg.Replace != "" {
+			fmt.Printf("   replaced by: %s\n", cfg.Replace)
+		} else {
+			versions, err := plugins.InstalledVersions(cfg.Source)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("   installed: %t\n", cfg.IsInstalled())
+			fmt.Printf("   installed versions: %s\n", strings.Join(versions, ", "))
+		}
+
+		if !cfg.IsInstalled() {
+			continue
+		}
+
+		// Start the plugin to determine the negotiated protocol version. Plugins are not downloaded here
+		pr := plugins.NewPluginRepository()
+		pr.SetLockFile(lock)
+		if err := pr.LoadPlugin(cmd.Context(), name, cfg); err != nil {
+			fmt.Printf("   protocol: unknown (%s)\n", err)
+		} else {
+			handler, err := pr.Get(cmd.Context(), name)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("   protocol: %d\n", handler.ProtocolVersion)
+		}
+		pr.Close()
+	}
+
+	fmt.Println("")
+	return nil
+}
+
+func pluginsInstallFunc(_ *cobra.Command) error {
+	configs, err := config.OpenPluginConfigs(commonFlags.configFile)
+	if err != nil {
+		return err
+	}
+
+	lock, err := plugins.LoadLockFile(config.LockFilePath(commonFlags.configFile))
+	if err != nil {
+		return err
+	}
+
+	for _, name := range pie.Sort(pie.Keys(configs)) {
+		if err := plugins.InstallPlugin(configs[name], lock); err != nil {
+			return err
+		}
+		log.Info().Msgf("Installed plugin %s %s", configs[name].Source, configs[name].Version)
+	}
+
+	return lock.Save()
+}
+
+func pluginsVerifyFunc(_ *cobra.Command) error {
+	configs, err := config.OpenPluginConfigs(commonFlags.configFile)
+	if err != nil {
+		return err
+	}
+
+	lock, err := plugins.LoadLockFile(config.LockFilePath(commonFlags.configFile))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, name := range pie.Sort(pie.Keys(configs)) {
+		if err := plugins.VerifyPlugin(configs[name], lock); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		log.Info().Msgf("Verified plugin %s %s", configs[name].Source, configs[name].Version)
+	}
+
+	if len(errs) > 0 {
+		return cli.NewGroupedError(fmt.Sprintf("plugin verification failed (%d errors)", len(errs)), errs)
+	}
+	return nil
+}
+
+func pluginsCleanFunc(_ *cobra.Command) error {
+	configs, err := config.OpenPluginConfigs(commonFlags.configFile)
+	if err != nil {
+		return err
+	}
+
+	removed, err := plugins.CleanPlugins(configs, pluginsFlags.dryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range removed {
+		if pluginsFlags.dryRun {
+			log.Info().Msgf("Would remove %s", dir)
+		} else {
+			log.Info().Msgf("Removed %s", dir)
+		}
+	}
+
+	if len(removed) == 0 {
+		log.Info().Msg("No unused plugin versions found")
+	}
+	return nil
+}
+
+func pluginsMirrorFunc(_ *cobra.Command, dir string) error {
+	configs, err := config.OpenPluginConfigs(commonFlags.configFile)
+	if err != nil {
+		return err
+	}
+
+	platforms, err := parsePlatforms(pluginsFlags.platforms)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range pie.Sort(pie.Keys(configs)) {
+		cfg := configs[name]
+		if cfg.Replace != "" {
+			log.Info().Msgf("Skipping plugin %s since it is replaced by %s", name, cfg.Replace)
+			continue
+		}
+
+		for _, platform := range platforms {
+			log.Info().Msgf("Mirroring plugin %s %s (%s)", cfg.Source, cfg.Version, platform)
+			if err := plugins.MirrorPlugin(cfg, platform, dir); err != nil {
+				return err
+			}
+		}
+	}
+
+	log.Info().Msgf("Mirrored plugins to %s", dir)
+	return nil
+}
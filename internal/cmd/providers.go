@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mach-composer/mach-composer-cli/internal/batcher"
+	"github.com/mach-composer/mach-composer-cli/internal/generator"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/hash"
+	"github.com/mach-composer/mach-composer-cli/internal/plugins"
+	"github.com/mach-composer/mach-composer-cli/internal/runner"
+	"github.com/spf13/cobra"
+)
+
+var providersFlags struct {
+	platforms []string
+}
+
+var providersCmd = &cobra.Command{
+	Use:   "providers",
+	Short: "Manage the terraform providers used by the sites and components",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var providersLockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Update the terraform dependency lock files of all sites and components",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		preprocessCommonFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return providersLockFunc(cmd)
+	},
+}
+
+func init() {
+	registerCommonFlags(providersLockCmd)
+	registerRunnerFlags(providersLockCmd)
+	providersLockCmd.Flags().StringArrayVarP(&providersFlags.platforms, "platform", "", nil,
+		"Platform to record checksums for, in the form os_arch. Can be repeated. Defaults to the platforms in the "+
+			"config, or the current platform")
+
+	providersCmd.AddCommand(providersLockCmd)
+}
+
+func providersLockFunc(cmd *cobra.Command) error {
+	cfg := loadConfig(cmd, true)
+	defer cfg.Close()
+	ctx := cmd.Context()
+
+	if cfg.MachComposer.Terraform.LockDir == "" {
+		return fmt.Errorf("no lock directory configured, set mach_composer.terraform.lock_dir to keep the lock files")
+	}
+
+	platforms := providersFlags.platforms
+	if len(platforms) == 0 {
+		platforms = cfg.MachComposer.Terraform.Platforms
+	}
+	for _, p := range platforms {
+		if _, err := plugins.ParsePlatform(p); err != nil {
+			return err
+		}
+	}
+
+	dg, err := graph.ToDeploymentGraph(cfg, commonFlags.outputPath)
+	if err != nil {
+		return err
+	}
+
+	if err := generator.Write(ctx, cfg, dg, nil); err != nil {
+		return err
+	}
+
+	r := runner.NewGraphRunner(
+		batcher.NaiveBatchFunc(),
+		hash.Factory(cfg),
+		commonFlags.workers,
+	)
+
+	return runGraph(ctx, cfg, r, false, func(ctx context.Context) error {
+		return r.TerraformProvidersLock(ctx, dg, &runner.ProvidersLockOptions{
+			Platforms: platforms,
+		})
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/mach-composer/mach-composer-cli/internal/cli"
 	"github.com/mach-composer/mach-composer-cli/internal/cmd/cloudcmd"
+	"github.com/mattn/go-isatty"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -67,6 +68,24 @@ var (
 				ctx = cli.ContextWithGithubCI(ctx)
 			}
 
+			groupOutput, err := cmd.Flags().GetBool("group-output")
+			if err != nil {
+				panic(err)
+			}
+			// GitHub log groups only make sense when the output of a node is written at once
+			if groupOutput || github {
+				ctx = cli.ContextWithGroupedOutput(ctx)
+			}
+
+			noTUI, err := cmd.Flags().GetBool("no-tui")
+			if err != nil {
+				panic(err)
+			}
+			if !noTUI && !groupOutput && !github && !quiet && output == string(cli.OutputTypeConsole) &&
+				isatty.IsTerminal(os.Stdout.Fd()) && isatty.IsTerminal(os.Stdin.Fd()) {
+				ctx = cli.ContextWithTerminalUI(ctx)
+			}
+
 			//Load logger into context and global logger
 			ctx = logger.WithContext(ctx)
 			log.Logger = logger
@@ -95,12 +114,17 @@ func init() {
 	RootCmd.PersistentFlags().BoolP("quiet", "q", false, "Quiet output. This is equal to setting log levels to error and higher")
 	RootCmd.PersistentFlags().String("output", "console", "The output type. One of: console, json")
 	RootCmd.PersistentFlags().BoolP("github", "g", false, "Whether logs should be decorated with github-specific formatting")
+	RootCmd.PersistentFlags().Bool("no-tui", false, "Disable the interactive terminal UI that is shown when running in a terminal")
+	RootCmd.PersistentFlags().Bool("group-output", false, "Buffer the terraform output of every component and write it at once when it is done, instead of streaming it")
 	RootCmd.AddCommand(applyCmd)
 	RootCmd.AddCommand(cloudcmd.CloudCmd)
 	RootCmd.AddCommand(componentsCmd)
+	RootCmd.AddCommand(fmtCmd)
 	RootCmd.AddCommand(generateCmd)
 	RootCmd.AddCommand(initCmd)
 	RootCmd.AddCommand(planCmd)
+	RootCmd.AddCommand(pluginsCmd)
+	RootCmd.AddCommand(providersCmd)
 	RootCmd.AddCommand(schemaCmd)
 	RootCmd.AddCommand(showPlanCmd)
 	RootCmd.AddCommand(sitesCmd)
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"github.com/mach-composer/mach-composer-cli/internal/batcher"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
 	"github.com/mach-composer/mach-composer-cli/internal/hash"
@@ -29,6 +30,7 @@ var showPlanCmd = &cobra.Command{
 
 func init() {
 	registerCommonFlags(showPlanCmd)
+	registerRunnerFlags(showPlanCmd)
 	showPlanCmd.Flags().BoolVarP(&showPlanFlags.forceInit, "force-init", "", false, "Force terraform initialization. By default mach-composer will reuse existing terraform resources")
 	showPlanCmd.Flags().BoolVarP(&showPlanFlags.noColor, "no-color", "", false, "Disable color output")
 	showPlanCmd.Flags().BoolVarP(&showPlanFlags.ignoreChangeDetection, "ignore-change-detection", "", false,
@@ -51,9 +53,11 @@ func showPlanFunc(cmd *cobra.Command, _ []string) error {
 		commonFlags.workers,
 	)
 
-	return r.TerraformShow(ctx, dg, &runner.ShowPlanOptions{
-		ForceInit:             showPlanFlags.forceInit,
-		NoColor:               showPlanFlags.noColor,
-		IgnoreChangeDetection: showPlanFlags.ignoreChangeDetection,
+	return runGraph(ctx, cfg, r, false, func(ctx context.Context) error {
+		return r.TerraformShow(ctx, dg, &runner.ShowPlanOptions{
+			ForceInit:             showPlanFlags.forceInit,
+			NoColor:               showPlanFlags.noColor,
+			IgnoreChangeDetection: showPlanFlags.ignoreChangeDetection,
+		})
 	})
 }
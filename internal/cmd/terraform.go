@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"github.com/mach-composer/mach-composer-cli/internal/batcher"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
 	"github.com/mach-composer/mach-composer-cli/internal/hash"
@@ -27,6 +28,7 @@ var terraformCmd = &cobra.Command{
 
 func init() {
 	registerCommonFlags(terraformCmd)
+	registerRunnerFlags(terraformCmd)
 	terraformCmd.Flags().BoolVarP(&terraformFlags.ignoreChangeDetection, "ignore-change-detection", "", true,
 		"Ignore change detection to run even if the components are considered up to date. Per default the proxy will ignore change detection")
 }
@@ -47,8 +49,10 @@ func terraformFunc(cmd *cobra.Command, args []string) error {
 		commonFlags.workers,
 	)
 
-	return r.TerraformProxy(ctx, dg, &runner.ProxyOptions{
-		Command:               args,
-		IgnoreChangeDetection: terraformFlags.ignoreChangeDetection,
+	return runGraph(ctx, cfg, r, false, func(ctx context.Context) error {
+		return r.TerraformProxy(ctx, dg, &runner.ProxyOptions{
+			Command:               args,
+			IgnoreChangeDetection: terraformFlags.ignoreChangeDetection,
+		})
 	})
 }
@@ -8,23 +8,4 @@ terraform {
     dynamodb_table = "lock-table"
     encrypt        = true
   }
-  required_providers {
-    aws = {
-      version = "~> 3.74.1"
-    }
-  }
-}
-
-# File sources
-# Resources
-# Configuring AWS
-provider "aws" {
-  region = "eu-west-1"
-}
-
-locals {
-  tags = {
-    Site        = "test-1"
-    Environment = "test"
-  }
 }
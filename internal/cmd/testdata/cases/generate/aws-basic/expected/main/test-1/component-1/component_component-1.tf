@@ -1,17 +1,5 @@
 # This file is auto-generated by MACH composer
 # SiteComponent: test-1/component-1
-terraform {
-  backend "azurerm" {
-    key                  = "test-1/component-1"
-    storage_account_name = "storageaccount"
-    container_name       = "container-name"
-    resource_group_name  = "resourcegroupid"
-  }
-  required_providers {}
-}
-
-# File sources
-# Resources
 # Component: component-1
 module "component-1" {
   source            = "{{ .PWD }}/testdata/modules/application"
@@ -21,6 +9,9 @@ module "component-1" {
   environment       = "test"
   site              = "test-1"
   tags              = local.tags
+  providers = {
+    aws = aws,
+  }
 }
 
 output "component-1" {
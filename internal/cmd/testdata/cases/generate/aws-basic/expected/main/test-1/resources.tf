@@ -0,0 +1,8 @@
+# This file is auto-generated by MACH composer
+# site: test-1
+locals {
+  tags = {
+    Site        = "test-1"
+    Environment = "test"
+  }
+}
@@ -0,0 +1,11 @@
+# This file is auto-generated by MACH composer
+# SiteComponent: test-1/component-2
+terraform {
+  backend "s3" {
+    bucket         = "state-bucket"
+    key            = "mach-composer/test-1/component-2"
+    region         = "eu-west-1"
+    dynamodb_table = "lock-table"
+    encrypt        = true
+  }
+}
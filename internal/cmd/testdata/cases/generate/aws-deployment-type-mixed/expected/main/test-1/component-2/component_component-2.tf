@@ -0,0 +1,15 @@
+# This file is auto-generated by MACH composer
+# SiteComponent: test-1/component-2
+# Component: component-2
+module "component-2" {
+  source = "{{ .PWD }}/testdata/modules/application"
+  variables = {
+    parent_names = [data.terraform_remote_state.test-1.outputs.component-1.name]
+  }
+}
+
+output "component-2" {
+  description = "The module outputs for component-2"
+  sensitive   = true
+  value       = module.component-2
+}
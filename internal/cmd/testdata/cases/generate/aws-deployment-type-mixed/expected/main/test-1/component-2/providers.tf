@@ -0,0 +1,5 @@
+# This file is auto-generated by MACH composer
+# SiteComponent: test-1/component-2
+terraform {
+  required_providers {}
+}
@@ -0,0 +1,12 @@
+# This file is auto-generated by MACH composer
+# SiteComponent: test-1/component-2
+data "terraform_remote_state" "test-1" {
+  backend = "s3"
+  config = {
+    bucket         = "state-bucket"
+    key            = "mach-composer/test-1"
+    region         = "eu-west-1"
+    dynamodb_table = "lock-table"
+    encrypt        = true
+  }
+}
@@ -0,0 +1,12 @@
+# This file is auto-generated by MACH composer
+# site: test-1
+# Component: component-1
+module "component-1" {
+  source = "{{ .PWD }}/testdata/modules/application"
+}
+
+output "component-1" {
+  description = "The module outputs for component-1"
+  sensitive   = true
+  value       = module.component-1
+}
@@ -0,0 +1,12 @@
+# This file is auto-generated by MACH composer
+# site: test-1
+# Component: component-2
+module "component-2" {
+  source = "{{ .PWD }}/testdata/modules/application"
+}
+
+output "component-2" {
+  description = "The module outputs for component-2"
+  sensitive   = true
+  value       = module.component-2
+}
@@ -1,18 +1,5 @@
 # This file is auto-generated by MACH composer
 # SiteComponent: test-1/component-1
-terraform {
-  backend "s3" {
-    bucket         = "state-bucket"
-    key            = "mach-composer/test-1/component-1"
-    region         = "eu-west-1"
-    dynamodb_table = "lock-table"
-    encrypt        = true
-  }
-  required_providers {}
-}
-
-# File sources
-# Resources
 # Component: component-1
 module "component-1" {
   source = "{{ .PWD }}/testdata/modules/application"
@@ -8,8 +8,4 @@ terraform {
     dynamodb_table = "lock-table"
     encrypt        = true
   }
-  required_providers {}
 }
-
-# File sources
-# Resources
\ No newline at end of file
@@ -0,0 +1,11 @@
+# This file is auto-generated by MACH composer
+# SiteComponent: test-2/component-1
+terraform {
+  backend "s3" {
+    bucket         = "state-bucket"
+    key            = "mach-composer/test-2/component-1"
+    region         = "eu-west-1"
+    dynamodb_table = "lock-table"
+    encrypt        = true
+  }
+}
@@ -0,0 +1,5 @@
+# This file is auto-generated by MACH composer
+# SiteComponent: test-2/component-1
+terraform {
+  required_providers {}
+}
@@ -0,0 +1,5 @@
+# This file is auto-generated by MACH composer
+# site: test-2
+terraform {
+  required_providers {}
+}
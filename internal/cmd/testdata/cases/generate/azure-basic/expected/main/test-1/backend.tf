@@ -7,8 +7,4 @@ terraform {
     container_name       = "container-name"
     resource_group_name  = "resourcegroupid"
   }
-  required_providers {}
 }
-
-# File sources
-# Resources
@@ -0,0 +1,10 @@
+# This file is auto-generated by MACH composer
+# SiteComponent: test-1/component-1
+terraform {
+  backend "azurerm" {
+    key                  = "test-1/component-1"
+    storage_account_name = "storageaccount"
+    container_name       = "container-name"
+    resource_group_name  = "resourcegroupid"
+  }
+}
@@ -0,0 +1,18 @@
+# This file is auto-generated by MACH composer
+# SiteComponent: test-1/component-1
+# Component: component-1
+module "component-1" {
+  source            = "{{ .PWD }}/testdata/modules/application"
+  variables         = {}
+  secrets           = {}
+  component_version = "test"
+  environment       = "test"
+  site              = "test-1"
+  tags              = local.tags
+}
+
+output "component-1" {
+  description = "The module outputs for component-1"
+  sensitive   = true
+  value       = module.component-1
+}
@@ -0,0 +1,5 @@
+# This file is auto-generated by MACH composer
+# site: test-1
+terraform {
+  required_providers {}
+}
@@ -0,0 +1,8 @@
+# This file is auto-generated by MACH composer
+# site: test-1
+terraform {
+  backend "gcs" {
+    bucket = "state-bucket"
+    prefix = "mach-composer/test-1"
+  }
+}
@@ -0,0 +1,8 @@
+# This file is auto-generated by MACH composer
+# SiteComponent: test-1/component-1
+terraform {
+  backend "gcs" {
+    bucket = "state-bucket"
+    prefix = "mach-composer/test-1/component-1"
+  }
+}
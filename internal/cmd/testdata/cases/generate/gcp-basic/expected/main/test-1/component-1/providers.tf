@@ -0,0 +1,16 @@
+# This file is auto-generated by MACH composer
+# SiteComponent: test-1/component-1
+terraform {
+  required_providers {
+    google = {
+      source  = "hashicorp/google"
+      version = "~> 4.69.1"
+    }
+  }
+}
+
+provider "google" {
+  project = ""
+  region  = ""
+  zone    = ""
+}
@@ -1,10 +1,6 @@
 # This file is auto-generated by MACH composer
 # site: test-1
 terraform {
-  backend "gcs" {
-    bucket = "state-bucket"
-    prefix = "mach-composer/test-1"
-  }
   required_providers {
     google = {
       source  = "hashicorp/google"
@@ -13,8 +9,6 @@ terraform {
   }
 }
 
-# File sources
-# Resources
 provider "google" {
   project = ""
   region  = ""
@@ -0,0 +1,8 @@
+# This file is auto-generated by MACH composer
+# SiteComponent: test-1/component-1
+locals {
+  tags = {
+    Site        = "test-1"
+    Environment = "test"
+  }
+}
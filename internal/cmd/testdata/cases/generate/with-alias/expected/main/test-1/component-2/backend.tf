@@ -0,0 +1,7 @@
+# This file is auto-generated by MACH composer
+# SiteComponent: test-1/component-2
+terraform {
+  backend "local" {
+    path = "./states/test-1/component-2.tfstate"
+  }
+}
@@ -0,0 +1,7 @@
+# This file is auto-generated by MACH composer
+# site: test-1
+terraform {
+  backend "local" {
+    path = "./states/test-1.tfstate"
+  }
+}
@@ -0,0 +1,14 @@
+# This file is auto-generated by MACH composer
+# site: test-1
+terraform {
+  required_providers {
+    aws = {
+      version = "~> 3.74.1"
+    }
+  }
+}
+
+# Configuring AWS
+provider "aws" {
+  region = "eu-west-1"
+}
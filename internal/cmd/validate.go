@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"github.com/mach-composer/mach-composer-cli/internal/batcher"
 	"github.com/mach-composer/mach-composer-cli/internal/cli"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
@@ -39,6 +40,7 @@ var validateCmd = &cobra.Command{
 
 func init() {
 	registerCommonFlags(validateCmd)
+	registerRunnerFlags(validateCmd)
 	validateCmd.Flags().StringVarP(&validateFlags.validationPath, "validation-path", "", "validations",
 		"Directory path to store files required for configuration validation.")
 
@@ -73,5 +75,7 @@ func validateFunc(cmd *cobra.Command, _ []string) error {
 		commonFlags.workers,
 	)
 
-	return r.TerraformValidate(ctx, dg)
+	return runGraph(ctx, cfg, r, true, func(ctx context.Context) error {
+		return r.TerraformValidate(ctx, dg)
+	})
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"github.com/elliotchance/pie/v2"
 	"github.com/mach-composer/mach-composer-cli/internal/utils"
@@ -17,9 +18,27 @@ type ComponentConfig struct {
 	Branch       string            `yaml:"branch"`
 	Integrations []string          `yaml:"integrations"`
 	Endpoints    map[string]string `yaml:"endpoints"`
+	// Outputs are the outputs of the component that can be referenced. When set every output is rendered as a
+	// separate terraform output, instead of a single sensitive output holding all of them
+	Outputs []ComponentOutput `yaml:"outputs"`
+	// Terraform are settings that are merged into the terraform configuration of the nodes deploying the component
+	Terraform *ComponentTerraform `yaml:"terraform"`
 }
 
-func parseComponentsNode(cfg *MachConfig, node *yaml.Node) error {
+type ComponentOutput struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Sensitive   bool   `yaml:"sensitive"`
+}
+
+// HasOutput returns whether the component declares an output with the given name
+func (c *ComponentConfig) HasOutput(name string) bool {
+	return pie.Any(c.Outputs, func(o ComponentOutput) bool {
+		return o.Name == name
+	})
+}
+
+func parseComponentsNode(ctx context.Context, cfg *MachConfig, node *yaml.Node) error {
 	if err := node.Decode(&cfg.Components); err != nil {
 		return fmt.Errorf("decoding error: %w", err)
 	}
@@ -45,7 +64,7 @@ func parseComponentsNode(cfg *MachConfig, node *yaml.Node) error {
 			}
 
 			data = utils.FilterMap(data, []string{
-				"name", "source", "version", "branch", "integrations", "endpoints", "paths",
+				"name", "source", "version", "branch", "integrations", "endpoints", "paths", "outputs", "terraform",
 			})
 
 			if err := plugin.SetComponentConfig(componentName, version, data); err != nil {
@@ -54,18 +73,18 @@ func parseComponentsNode(cfg *MachConfig, node *yaml.Node) error {
 		}
 	}
 
-	if err := registerComponentEndpoints(cfg); err != nil {
+	if err := registerComponentEndpoints(ctx, cfg); err != nil {
 		return fmt.Errorf("register of components failed: %w", err)
 	}
 
 	return nil
 }
 
-func registerComponentEndpoints(cfg *MachConfig) error {
+func registerComponentEndpoints(ctx context.Context, cfg *MachConfig) error {
 	var cloudPlugin schema.MachComposerPlugin
 	if cfg.Global.Cloud != "" {
 		var err error
-		cloudPlugin, err = cfg.Plugins.Get(cfg.Global.Cloud)
+		cloudPlugin, err = cfg.Plugins.Get(ctx, cfg.Global.Cloud)
 		if err != nil {
 			return err
 		}
@@ -110,6 +129,18 @@ func verifyComponents(cfg *MachConfig) error {
 			c.Integrations = append(c.Integrations, cfg.Global.Cloud)
 		}
 
+		var outputs []string
+		for _, o := range c.Outputs {
+			if pie.Contains(outputs, o.Name) {
+				return fmt.Errorf("output %s of component %s is duplicate", o.Name, c.Name)
+			}
+			outputs = append(outputs, o.Name)
+		}
+
+		if err := verifyComponentTerraform(c.Name, c.Terraform); err != nil {
+			return err
+		}
+
 		seen = append(seen, c.Name)
 	}
 
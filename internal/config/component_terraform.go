@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// ComponentTerraform are terraform settings of a component, which are merged into the terraform configuration of the
+// site or site component that deploys it
+type ComponentTerraform struct {
+	// RequiredVersion is a terraform version constraint, like `>= 1.5`
+	RequiredVersion string `yaml:"required_version"`
+	// RequiredProviders are required next to the providers of the plugins
+	RequiredProviders map[string]RequiredProvider `yaml:"required_providers"`
+	// Providers is HCL with provider configuration blocks
+	Providers string `yaml:"providers"`
+}
+
+type RequiredProvider struct {
+	Source  string `yaml:"source"`
+	Version string `yaml:"version"`
+}
+
+// verifyComponentTerraform checks that the required version is a valid version constraint, and that the provider
+// configuration only contains valid provider blocks
+func verifyComponentTerraform(name string, t *ComponentTerraform) error {
+	if t == nil {
+		return nil
+	}
+	if t.RequiredVersion != "" {
+		if _, err := version.NewConstraint(t.RequiredVersion); err != nil {
+			return fmt.Errorf("terraform required_version of component %s is invalid: %w", name, err)
+		}
+	}
+	if t.Providers == "" {
+		return nil
+	}
+
+	file, diags := hclsyntax.ParseConfig([]byte(t.Providers), name+".providers", hcl.InitialPos)
+	if diags.HasErrors() {
+		return fmt.Errorf("terraform providers of component %s are invalid: %w", name, diags)
+	}
+
+	body := file.Body.(*hclsyntax.Body)
+	if len(body.Attributes) > 0 {
+		return fmt.Errorf("terraform providers of component %s can only contain provider blocks", name)
+	}
+	for _, block := range body.Blocks {
+		if block.Type != "provider" || len(block.Labels) != 1 {
+			return fmt.Errorf("terraform providers of component %s can only contain provider blocks, found %s",
+				name, block.Type)
+		}
+	}
+	return nil
+}
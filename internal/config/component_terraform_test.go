@@ -0,0 +1,28 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyComponentTerraform(t *testing.T) {
+	assert.NoError(t, verifyComponentTerraform("api", nil))
+	assert.NoError(t, verifyComponentTerraform("api", &ComponentTerraform{
+		Providers: "provider \"random\" {}\nprovider \"aws\" {\n  alias = \"us\"\n}\n",
+	}))
+
+	assert.NoError(t, verifyComponentTerraform("api", &ComponentTerraform{RequiredVersion: ">= 1.5, < 2.0"}))
+
+	err := verifyComponentTerraform("api", &ComponentTerraform{RequiredVersion: `>= 1.5" } resource "a" "b" {`})
+	assert.ErrorContains(t, err, "terraform required_version of component api is invalid")
+
+	err = verifyComponentTerraform("api", &ComponentTerraform{Providers: "resource \"a\" \"b\" {}\n"})
+	assert.EqualError(t, err, "terraform providers of component api can only contain provider blocks, found resource")
+
+	err = verifyComponentTerraform("api", &ComponentTerraform{Providers: "region = \"eu-west-1\"\n"})
+	assert.EqualError(t, err, "terraform providers of component api can only contain provider blocks")
+
+	err = verifyComponentTerraform("api", &ComponentTerraform{Providers: "provider \"aws\" {\n"})
+	assert.ErrorContains(t, err, "terraform providers of component api are invalid")
+}
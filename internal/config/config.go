@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/mach-composer/mach-composer-cli/internal/plugins"
 	"github.com/mach-composer/mach-composer-cli/internal/state"
 	"github.com/mach-composer/mcc-sdk-go/mccsdk"
@@ -37,11 +39,52 @@ func (c *MachConfig) HasSite(ident string) bool {
 }
 
 type MachComposer struct {
-	Version       any                         `yaml:"version"`
-	VariablesFile string                      `yaml:"variables_file"`
-	Plugins       map[string]MachPluginConfig `yaml:"plugins"`
-	Cloud         MachComposerCloud           `yaml:"cloud"`
-	Deployment    Deployment                  `yaml:"deployment"`
+	Version        any                         `yaml:"version"`
+	VariablesFile  string                      `yaml:"variables_file"`
+	Plugins        map[string]MachPluginConfig `yaml:"plugins"`
+	PluginRegistry string                      `yaml:"plugin_registry"`
+	PluginMirror   string                      `yaml:"plugin_mirror"`
+	Cloud          MachComposerCloud           `yaml:"cloud"`
+	Deployment     Deployment                  `yaml:"deployment"`
+	Policies       []PolicyRule                `yaml:"policies"`
+	Terraform      MachComposerTerraform       `yaml:"terraform"`
+	// TemplatesDir is the directory with templates that override the templates used to generate the terraform code
+	TemplatesDir string `yaml:"templates_dir"`
+}
+
+// MachComposerTerraform configures how terraform commands are run
+type MachComposerTerraform struct {
+	// Binary is either `terraform` or `tofu`. Defaults to `terraform`
+	Binary string `yaml:"binary"`
+	// Version is a version constraint the binary should match, for example `1.7.x` or `>= 1.5, < 2.0`
+	Version string `yaml:"version"`
+	// Install downloads a matching version to the cache directory when the binary on the PATH does not match
+	Install bool `yaml:"install"`
+	// LockDir is the directory, relative to the config file, in which the dependency lock files of all sites and
+	// components are kept
+	LockDir string `yaml:"lock_dir"`
+	// Platforms are the platforms to record provider checksums for in the lock files, in the form os_arch
+	Platforms []string `yaml:"platforms"`
+	// Retries retries terraform commands that fail with a transient error
+	Retries RetryConfig `yaml:"retries"`
+}
+
+// RetryConfig configures when failed terraform init, plan and apply commands are run again
+type RetryConfig struct {
+	// MaxAttempts is the number of times a command is run at most. Defaults to 1, which disables retries
+	MaxAttempts int `yaml:"max_attempts"`
+	// Delay is the time to wait before the first retry. It is doubled for every next attempt
+	Delay time.Duration `yaml:"delay"`
+	// MaxDelay is the maximum time to wait between attempts
+	MaxDelay time.Duration `yaml:"max_delay"`
+	// Patterns are regular expressions matched against the output of failed commands. Only failures that match are
+	// retried. Defaults to state lock errors, rate limits and server errors
+	Patterns []string `yaml:"patterns"`
+}
+
+// HasBinary returns whether a specific binary or version is configured
+func (c MachComposerTerraform) HasBinary() bool {
+	return c.Binary != "" || c.Version != ""
 }
 
 func (mc *MachComposer) CloudEnabled() bool {
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExtraTerraform is terraform code that is added to a generated root module, like `locals`, provider aliases, `data`
+// sources or `check` blocks. It is either the path of a file, relative to the config file, or inline HCL.
+type ExtraTerraform string
+
+// IsInline returns true when the value is HCL instead of a file path. HCL that defines anything contains a block or
+// an attribute, so it spans multiple lines or contains `{` or `=`, which file paths do not.
+func (e ExtraTerraform) IsInline() bool {
+	return strings.ContainsAny(string(e), "\n{=")
+}
+
+// Name describes where the terraform code comes from
+func (e ExtraTerraform) Name() string {
+	if e.IsInline() {
+		return "inline"
+	}
+	return string(e)
+}
+
+// Content returns the terraform code, reading it from the file if it is not inline
+func (e ExtraTerraform) Content() ([]byte, error) {
+	if e.IsInline() {
+		return []byte(e), nil
+	}
+	return os.ReadFile(string(e))
+}
+
+// resolveExtraTerraform makes the paths of all extra terraform files relative to the directory of the config file
+func resolveExtraTerraform(cfg *MachConfig, dir string) {
+	resolve := func(extras []ExtraTerraform) {
+		for i, e := range extras {
+			if !e.IsInline() && !filepath.IsAbs(string(e)) {
+				extras[i] = ExtraTerraform(filepath.Join(dir, string(e)))
+			}
+		}
+	}
+
+	resolve(cfg.Global.ExtraTerraform)
+	for i := range cfg.Sites {
+		resolve(cfg.Sites[i].ExtraTerraform)
+		for j := range cfg.Sites[i].Components {
+			resolve(cfg.Sites[i].Components[j].ExtraTerraform)
+		}
+	}
+}
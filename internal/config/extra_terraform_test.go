@@ -0,0 +1,30 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtraTerraformIsInline(t *testing.T) {
+	tests := map[ExtraTerraform]bool{
+		"locals {\n  a = 1\n}\n":    true,
+		"locals { a = 1 }":          true,
+		`provider "aws" {}`:         true,
+		"terraform/extra.tf":        false,
+		"../shared/locals.tf":       false,
+		"/etc/mach/provider-aws.tf": false,
+	}
+	for e, inline := range tests {
+		assert.Equal(t, inline, e.IsInline(), string(e))
+	}
+}
+
+func TestResolveExtraTerraform(t *testing.T) {
+	cfg := &MachConfig{Global: GlobalConfig{ExtraTerraform: []ExtraTerraform{"locals { a = 1 }", "extra.tf"}}}
+	resolveExtraTerraform(cfg, "config")
+
+	assert.Equal(t, []ExtraTerraform{"locals { a = 1 }", ExtraTerraform(filepath.Join("config", "extra.tf"))},
+		cfg.Global.ExtraTerraform)
+}
@@ -16,6 +16,8 @@ type GlobalConfig struct {
 
 	Variables variable.VariablesMap `yaml:"variables"`
 	Secrets   variable.VariablesMap `yaml:"secrets"`
+
+	ExtraTerraform []ExtraTerraform `yaml:"extra_terraform"`
 }
 
 type TerraformConfig struct {
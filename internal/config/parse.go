@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"path"
 	"path/filepath"
 	"strings"
 
+	"github.com/elliotchance/pie/v2"
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
 	"github.com/mach-composer/mach-composer-cli/internal/state"
 
 	"github.com/rs/zerolog/log"
@@ -121,9 +124,56 @@ func loadPlugins(ctx context.Context, raw *rawConfig) error {
 	}
 	raw.plugins = plugins.NewPluginRepository()
 
-	if len(raw.MachComposer.Plugins) == 0 {
+	lock, err := plugins.LoadLockFile(LockFilePath(raw.filename))
+	if err != nil {
+		return err
+	}
+	raw.plugins.SetLockFile(lock)
+
+	usesDefaultPlugins := len(raw.MachComposer.Plugins) == 0
+
+	pluginConfigs := PluginConfigs(&raw.MachComposer, filepath.Dir(raw.filename))
+	for _, pluginName := range pie.Sort(pie.Keys(pluginConfigs)) {
+		if err := raw.plugins.RegisterPlugin(pluginName, pluginConfigs[pluginName]); err != nil {
+			return err
+		}
+	}
+
+	// Only start the plugins that are actually used by the configuration
+	used := referencedPlugins(raw, raw.plugins.Registered())
+	for _, pluginName := range used {
+		if err := raw.plugins.StartPlugin(ctx, pluginName); err != nil {
+			return err
+		}
+	}
+
+	if usesDefaultPlugins {
+		cli.DeprecationWarning(&cli.DeprecationOptions{
+			Message: "loading default plugins is deprecated and will be removed in the next major version",
+			Details: fmt.Sprintf(`
+				No plugins are configured, so the default plugins are used. Only the default plugins that are
+				referenced by the configuration are started: %s
+
+				Please configure the plugins you use explicitly in the mach_composer.plugins block.
+			`, strings.Join(used, ", ")),
+		})
+	}
+
+	return raw.plugins.SaveLockFile()
+}
+
+// LockFilePath returns the path of the plugin lock file belonging to the given config file
+func LockFilePath(filename string) string {
+	return filepath.Join(filepath.Dir(filename), plugins.LockFileName)
+}
+
+// PluginConfigs returns the plugin configuration per plugin name. When no plugins are configured the default plugins
+// are returned. The registry and mirror can be overridden with the MC_PLUGIN_REGISTRY and MC_PLUGIN_MIRROR
+// environment variables, and a relative mirror directory is resolved relative to the given cwd
+func PluginConfigs(mc *MachComposer, cwd string) map[string]plugins.PluginConfig {
+	if len(mc.Plugins) == 0 {
 		log.Debug().Msg("No plugins specified; loading default plugins")
-		raw.MachComposer.Plugins = map[string]MachPluginConfig{
+		mc.Plugins = map[string]MachPluginConfig{
 			"amplience": {
 				Source:  "mach-composer/amplience",
 				Version: "0.1.3",
@@ -151,21 +201,49 @@ func loadPlugins(ctx context.Context, raw *rawConfig) error {
 		}
 	}
 
-	for pluginName, pluginData := range raw.MachComposer.Plugins {
-		pluginConfig := plugins.PluginConfig{
-			Source:  pluginData.Source,
-			Version: pluginData.Version,
-			Replace: pluginData.Replace,
-		}
-		if err := raw.plugins.LoadPlugin(ctx, pluginName, pluginConfig); err != nil {
-			return err
+	registry := mc.PluginRegistry
+	if value := os.Getenv("MC_PLUGIN_REGISTRY"); value != "" {
+		registry = value
+	}
+
+	mirror := mc.PluginMirror
+	if value := os.Getenv("MC_PLUGIN_MIRROR"); value != "" {
+		mirror = value
+	}
+	if mirror != "" && !filepath.IsAbs(mirror) {
+		mirror = filepath.Join(cwd, mirror)
+	}
+
+	result := make(map[string]plugins.PluginConfig, len(mc.Plugins))
+	for pluginName, pluginData := range mc.Plugins {
+		result[pluginName] = plugins.PluginConfig{
+			Source:   pluginData.Source,
+			Version:  pluginData.Version,
+			Replace:  pluginData.Replace,
+			Registry: registry,
+			Mirror:   mirror,
 		}
 	}
-	return nil
+	return result
+}
+
+// OpenPluginConfigs reads only the plugin configuration of the given config file, without starting any plugins
+func OpenPluginConfigs(filename string) (map[string]plugins.PluginConfig, error) {
+	document, err := loadYamlFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := newRawConfig(filename, document)
+	if err != nil {
+		return nil, err
+	}
+
+	return PluginConfigs(&raw.MachComposer, filepath.Dir(filename)), nil
 }
 
 // resolveConfig is responsible for parsing a mach composer yaml config file and creating the resulting MachConfig struct.
-func resolveConfig(_ context.Context, intermediate *rawConfig) (*MachConfig, error) {
+func resolveConfig(ctx context.Context, intermediate *rawConfig) (*MachConfig, error) {
 	if err := intermediate.validate(); err != nil {
 		return nil, err
 	}
@@ -187,14 +265,19 @@ func resolveConfig(_ context.Context, intermediate *rawConfig) (*MachConfig, err
 		return nil, fmt.Errorf("failed to parse global node: %w", err)
 	}
 
-	if err := parseComponentsNode(cfg, &intermediate.Components); err != nil {
+	if err := parseComponentsNode(ctx, cfg, &intermediate.Components); err != nil {
 		return nil, fmt.Errorf("failed to parse components node: %w", err)
 	}
 
-	if err := parseSitesNode(cfg, &intermediate.Sites); err != nil {
+	if err := parseSitesNode(ctx, cfg, &intermediate.Sites); err != nil {
 		return nil, fmt.Errorf("failed to parse sites node: %w", err)
 	}
 
+	resolveExtraTerraform(cfg, filepath.Dir(intermediate.filename))
+	if dir := cfg.MachComposer.TemplatesDir; dir != "" && !filepath.IsAbs(dir) {
+		cfg.MachComposer.TemplatesDir = filepath.Join(filepath.Dir(intermediate.filename), dir)
+	}
+
 	return cfg, nil
 }
 
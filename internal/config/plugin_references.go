@@ -0,0 +1,70 @@
+package config
+
+import (
+	"github.com/elliotchance/pie/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// referencedPlugins returns the names of the plugins that are used by the configuration. A plugin is used when it is
+// the global cloud, when it is listed in the integrations of a component, or when a global, site, site endpoint,
+// site component or component section is configured for it.
+func referencedPlugins(raw *rawConfig, names []string) []string {
+	var result []string
+	add := func(name string) {
+		if pie.Contains(names, name) && !pie.Contains(result, name) {
+			result = append(result, name)
+		}
+	}
+	addKeys := func(node *yaml.Node) {
+		if node == nil || node.Kind != yaml.MappingNode {
+			return
+		}
+		for key := range MapYamlNodes(node.Content) {
+			add(key)
+		}
+	}
+
+	globalNodes := MapYamlNodes(raw.Global.Content)
+	if cloud, ok := globalNodes["cloud"]; ok {
+		add(cloud.Value)
+	}
+	addKeys(&raw.Global)
+
+	for _, component := range raw.Components.Content {
+		addKeys(component)
+
+		nodes := MapYamlNodes(component.Content)
+		if integrations, ok := nodes["integrations"]; ok {
+			for _, integration := range integrations.Content {
+				add(integration.Value)
+			}
+		}
+	}
+
+	for _, site := range raw.Sites.Content {
+		addKeys(site)
+
+		nodes := MapYamlNodes(site.Content)
+		if endpoints, ok := nodes["endpoints"]; ok && endpoints.Kind == yaml.MappingNode {
+			for _, endpoint := range MapYamlNodes(endpoints.Content) {
+				addKeys(endpoint)
+			}
+		}
+
+		if components, ok := nodes["components"]; ok {
+			for _, component := range components.Content {
+				addKeys(component)
+
+				// Deprecated store variables are migrated to the commercetools plugin
+				componentNodes := MapYamlNodes(component.Content)
+				_, hasStoreVariables := componentNodes["store_variables"]
+				_, hasStoreSecrets := componentNodes["store_secrets"]
+				if hasStoreVariables || hasStoreSecrets {
+					add("commercetools")
+				}
+			}
+		}
+	}
+
+	return pie.Sort(result)
+}
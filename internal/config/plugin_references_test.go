@@ -0,0 +1,45 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestReferencedPlugins(t *testing.T) {
+	data := []byte(`
+mach_composer:
+  version: 1
+global:
+  environment: test
+  cloud: aws
+  sentry:
+    dsn: "https://sentry.io"
+sites:
+  - identifier: my-site
+    azure:
+      region: westeurope
+    components:
+      - name: my-component
+        store_variables:
+          my-store:
+            key: value
+components:
+  - name: my-component
+    source: ./component
+    integrations: ["contentful"]
+`)
+
+	document := &yaml.Node{}
+	require.NoError(t, yaml.Unmarshal(data, document))
+	raw, err := newRawConfig("main.yml", document)
+	require.NoError(t, err)
+
+	names := []string{"amplience", "aws", "azure", "commercetools", "contentful", "sentry"}
+	assert.Equal(t,
+		[]string{"aws", "azure", "commercetools", "contentful", "sentry"},
+		referencedPlugins(raw, names),
+	)
+}
@@ -0,0 +1,16 @@
+package config
+
+// PolicyRule blocks planned changes. A resource change violates the rule when it matches all fields that are set.
+// Patterns support the wildcards of path.Match.
+type PolicyRule struct {
+	Name string `yaml:"name"`
+	// ResourceTypes are patterns of terraform resource types, for example `aws_s3_bucket`
+	ResourceTypes []string `yaml:"resource_types"`
+	// Actions are the terraform actions the rule applies to. As a replaced resource is also deleted, `delete` matches
+	// replaced resources as well.
+	Actions      []string `yaml:"actions"`
+	Sites        []string `yaml:"sites"`
+	Components   []string `yaml:"components"`
+	Environments []string `yaml:"environments"`
+	Message      string   `yaml:"message"`
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// ImportConfig describes an existing resource that should be adopted into the terraform state by rendering a
+// terraform `import {}` block.
+type ImportConfig struct {
+	To       string `yaml:"to" json:"to"`
+	ID       string `yaml:"id" json:"id"`
+	Provider string `yaml:"provider" json:"provider,omitempty"`
+}
+
+// MovedConfig describes a resource address that has been renamed or moved by rendering a terraform `moved {}` block.
+type MovedConfig struct {
+	From string `yaml:"from" json:"from"`
+	To   string `yaml:"to" json:"to"`
+}
+
+func verifyImports(imports []ImportConfig) error {
+	for _, i := range imports {
+		if i.To == "" {
+			return fmt.Errorf("import is missing the 'to' address")
+		}
+		if err := verifyAddress(i.To, false); err != nil {
+			return fmt.Errorf("import 'to' address %q is invalid: %w", i.To, err)
+		}
+		if i.ID == "" {
+			return fmt.Errorf("import for %s is missing the 'id'", i.To)
+		}
+		if i.Provider != "" {
+			if err := verifyProvider(i.Provider); err != nil {
+				return fmt.Errorf("import provider %q is invalid: %w", i.Provider, err)
+			}
+		}
+	}
+	return nil
+}
+
+func verifyMoved(moved []MovedConfig) error {
+	for _, m := range moved {
+		if m.From == "" || m.To == "" {
+			return fmt.Errorf("moved block requires both 'from' and 'to' addresses")
+		}
+		if err := verifyAddress(m.From, true); err != nil {
+			return fmt.Errorf("moved 'from' address %q is invalid: %w", m.From, err)
+		}
+		if err := verifyAddress(m.To, true); err != nil {
+			return fmt.Errorf("moved 'to' address %q is invalid: %w", m.To, err)
+		}
+		if m.From == m.To {
+			return fmt.Errorf("moved block for %s has identical 'from' and 'to' addresses", m.From)
+		}
+	}
+	return nil
+}
+
+// addressPart is a name in a terraform address, optionally followed by an index like `[0]` or `["key"]`
+type addressPart struct {
+	name    string
+	indexed bool
+}
+
+// parseAddress splits a terraform address in its names, which are rendered as-is into the generated terraform
+func parseAddress(address string) ([]addressPart, error) {
+	traversal, diags := hclsyntax.ParseTraversalAbs([]byte(address), "address", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, errors.New(diags[0].Summary)
+	}
+
+	var parts []addressPart
+	for _, step := range traversal {
+		switch s := step.(type) {
+		case hcl.TraverseRoot:
+			parts = append(parts, addressPart{name: s.Name})
+		case hcl.TraverseAttr:
+			parts = append(parts, addressPart{name: s.Name})
+		case hcl.TraverseIndex:
+			if len(parts) == 0 || parts[len(parts)-1].indexed {
+				return nil, errors.New("unexpected index")
+			}
+			parts[len(parts)-1].indexed = true
+		default:
+			return nil, errors.New("unexpected expression")
+		}
+	}
+	return parts, nil
+}
+
+// verifyAddress checks that the address is a plain resource address, like `aws_s3_bucket.a` or
+// `module.a.aws_s3_bucket.b["key"]`. Module addresses, like `module.a`, are only allowed when module is set.
+func verifyAddress(address string, module bool) error {
+	parts, err := parseAddress(address)
+	if err != nil {
+		return err
+	}
+
+	for len(parts) >= 2 && parts[0].name == "module" && !parts[0].indexed {
+		parts = parts[2:]
+	}
+	if len(parts) == 0 {
+		if !module {
+			return errors.New("expected a resource address instead of a module address")
+		}
+		return nil
+	}
+
+	if parts[0].name == "data" && !parts[0].indexed {
+		parts = parts[1:]
+	}
+	if len(parts) != 2 || parts[0].indexed {
+		return errors.New("expected a resource address like aws_s3_bucket.name or module.name.aws_s3_bucket.name")
+	}
+	return nil
+}
+
+// verifyProvider checks that the provider is a provider reference, like `aws` or `aws.alias`
+func verifyProvider(provider string) error {
+	parts, err := parseAddress(provider)
+	if err != nil {
+		return err
+	}
+	if len(parts) > 2 || parts[0].indexed || parts[len(parts)-1].indexed {
+		return errors.New("expected a provider reference like aws or aws.alias")
+	}
+	return nil
+}
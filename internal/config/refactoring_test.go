@@ -0,0 +1,49 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyImports(t *testing.T) {
+	assert.NoError(t, verifyImports([]ImportConfig{
+		{To: "aws_s3_bucket.a", ID: "bucket"},
+		{To: `module.a["x"].aws_s3_bucket.b[0]`, ID: "bucket", Provider: "aws.us"},
+	}))
+
+	tests := map[string]ImportConfig{
+		"import 'to' address \"aws_s3_bucket.a\\n}\\nresource \\\"null_resource\\\" \\\"x\\\" {\" is invalid": {
+			To: "aws_s3_bucket.a\n}\nresource \"null_resource\" \"x\" {", ID: "bucket",
+		},
+		"import 'to' address \"module.a\" is invalid: expected a resource address instead of a module address": {
+			To: "module.a", ID: "bucket",
+		},
+		"import 'to' address \"aws_s3_bucket\" is invalid: expected a resource address like aws_s3_bucket.name or " +
+			"module.name.aws_s3_bucket.name": {To: "aws_s3_bucket", ID: "bucket"},
+		"import 'to' address \"var.a[0][1]\" is invalid: unexpected index": {To: "var.a[0][1]", ID: "bucket"},
+		"import provider \"aws.us.east\" is invalid: expected a provider reference like aws or aws.alias": {
+			To: "aws_s3_bucket.a", ID: "bucket", Provider: "aws.us.east",
+		},
+		"import provider \"aws = {}\" is invalid": {To: "aws_s3_bucket.a", ID: "bucket", Provider: "aws = {}"},
+	}
+	for expected, i := range tests {
+		assert.ErrorContains(t, verifyImports([]ImportConfig{i}), expected)
+	}
+}
+
+func TestVerifyMoved(t *testing.T) {
+	assert.NoError(t, verifyMoved([]MovedConfig{
+		{From: "aws_s3_bucket.a", To: "aws_s3_bucket.b"},
+		{From: "module.a", To: "module.b[0]"},
+	}))
+
+	err := verifyMoved([]MovedConfig{{From: "aws_s3_bucket.a", To: "aws_s3_bucket.a"}})
+	assert.EqualError(t, err, "moved block for aws_s3_bucket.a has identical 'from' and 'to' addresses")
+
+	err = verifyMoved([]MovedConfig{{From: "aws_s3_bucket.a", To: "aws_s3_bucket.b }\nresource \"x\" \"y\" {"}})
+	assert.ErrorContains(t, err, "moved 'to' address")
+
+	err = verifyMoved([]MovedConfig{{From: "\"aws_s3_bucket.a\"", To: "aws_s3_bucket.b"}})
+	assert.ErrorContains(t, err, "moved 'from' address")
+}
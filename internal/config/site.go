@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"github.com/mach-composer/mach-composer-cli/internal/config/variable"
 	"github.com/rs/zerolog/log"
@@ -23,20 +24,35 @@ func (s *SiteConfigs) Get(identifier string) (*SiteConfig, error) {
 	return nil, fmt.Errorf("site %s not found", identifier)
 }
 
+type ApprovalType string
+
+const (
+	// ApprovalRequired means changes are planned and only applied after they have been approved
+	ApprovalRequired ApprovalType = "required"
+	// ApprovalAuto means changes are applied without asking for confirmation
+	ApprovalAuto ApprovalType = "auto"
+)
+
 // SiteConfig contains all configuration needed for a site.
 type SiteConfig struct {
 	Name         string         `yaml:"name"`
 	Identifier   string         `yaml:"identifier"`
 	Deployment   *Deployment    `yaml:"deployment"`
+	Approval     ApprovalType   `yaml:"approval"`
 	RawEndpoints map[string]any `yaml:"endpoints"`
 
 	Variables variable.VariablesMap `yaml:"variables"`
 	Secrets   variable.VariablesMap `yaml:"secrets"`
 
 	Components SiteComponentConfigs `yaml:"components"`
+
+	Imports []ImportConfig `yaml:"imports"`
+	Moved   []MovedConfig  `yaml:"moved"`
+
+	ExtraTerraform []ExtraTerraform `yaml:"extra_terraform"`
 }
 
-func parseSitesNode(cfg *MachConfig, sitesNode *yaml.Node) error {
+func parseSitesNode(ctx context.Context, cfg *MachConfig, sitesNode *yaml.Node) error {
 	if err := sitesNode.Decode(&cfg.Sites); err != nil {
 		return fmt.Errorf("decoding error: %w", err)
 	}
@@ -71,7 +87,7 @@ func parseSitesNode(cfg *MachConfig, sitesNode *yaml.Node) error {
 				`,
 				Site: siteId,
 			})
-			if err := parseSiteEndpointNode(cfg, siteId, node); err != nil {
+			if err := parseSiteEndpointNode(ctx, cfg, siteId, node); err != nil {
 				return fmt.Errorf("failed to parse endpoints: %w", err)
 			}
 		}
@@ -82,6 +98,13 @@ func parseSitesNode(cfg *MachConfig, sitesNode *yaml.Node) error {
 	}
 
 	for k, s := range cfg.Sites {
+		if err := verifyImports(s.Imports); err != nil {
+			return fmt.Errorf("site %s: %w", s.Identifier, err)
+		}
+		if err := verifyMoved(s.Moved); err != nil {
+			return fmt.Errorf("site %s: %w", s.Identifier, err)
+		}
+
 		if s.Deployment == nil {
 			log.Debug().Msgf("No site deployment type specified for %s; defaulting to global setting", s.Identifier)
 			var siteDeployment = cfg.MachComposer.Deployment
@@ -92,7 +115,7 @@ func parseSitesNode(cfg *MachConfig, sitesNode *yaml.Node) error {
 	return resolveSiteComponents(cfg)
 }
 
-func parseSiteEndpointNode(cfg *MachConfig, siteId string, node *yaml.Node) error {
+func parseSiteEndpointNode(ctx context.Context, cfg *MachConfig, siteId string, node *yaml.Node) error {
 	nodes := MapYamlNodes(node.Content)
 	knownTags := []string{"url", "key", "zone", "throttling_rate_limit", "throttling_burst_limit"}
 
@@ -113,7 +136,7 @@ func parseSiteEndpointNode(cfg *MachConfig, siteId string, node *yaml.Node) erro
 				`),
 			})
 
-			cloudPlugin, err := cfg.Plugins.Get(cfg.Global.Cloud)
+			cloudPlugin, err := cfg.Plugins.Get(ctx, cfg.Global.Cloud)
 			if err != nil {
 				return err
 			}
@@ -245,6 +268,13 @@ func resolveSiteComponents(cfg *MachConfig) error {
 		for i := range site.Components {
 			c := &site.Components[i]
 
+			if err := verifyImports(c.Imports); err != nil {
+				return fmt.Errorf("site component %s: %w", c.Name, err)
+			}
+			if err := verifyMoved(c.Moved); err != nil {
+				return fmt.Errorf("site component %s: %w", c.Name, err)
+			}
+
 			if c.Deployment == nil {
 				log.Debug().Msgf("No site component deployment type specified for %s; defaulting to global setting", c.Name)
 				var siteComponentDeployment = *site.Deployment
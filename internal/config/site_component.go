@@ -26,6 +26,11 @@ type SiteComponentConfig struct {
 	Deployment *Deployment           `yaml:"deployment"`
 
 	DependsOn []string `yaml:"depends_on"`
+
+	Imports []ImportConfig `yaml:"imports"`
+	Moved   []MovedConfig  `yaml:"moved"`
+
+	ExtraTerraform []ExtraTerraform `yaml:"extra_terraform"`
 }
 
 func (sc *SiteComponentConfig) HasCloudIntegration(g *GlobalConfig) bool {
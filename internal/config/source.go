@@ -3,9 +3,18 @@ package config
 import (
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
+// DefaultRegistryHost is the registry used for registry sources without a hostname
+const DefaultRegistryHost = "registry.terraform.io"
+
+// registrySourcePattern matches `[<hostname>/]<namespace>/<name>/<provider>[//<subdir>]`. A hostname contains a dot or
+// a port, like `app.terraform.io` or `localhost:8080`.
+var registrySourcePattern = regexp.MustCompile(
+	`^(?:([a-zA-Z0-9-]+(?:\.[a-zA-Z0-9-]+)*(?::[0-9]+)?)/)?([a-zA-Z0-9][a-zA-Z0-9_-]*)/([a-zA-Z0-9][a-zA-Z0-9_-]*)/([a-zA-Z0-9]+)(?://(.+))?$`)
+
 type SourceType string
 
 const (
@@ -17,6 +26,7 @@ const (
 	SourceTypeHttp      SourceType = "http"
 	SourceTypeS3        SourceType = "s3"
 	SourceTypeGCS       SourceType = "gcs"
+	SourceTypeRegistry  SourceType = "registry"
 )
 
 type Source string
@@ -69,9 +79,48 @@ func (s *Source) Type() (SourceType, error) {
 		return SourceTypeGCS, nil
 	}
 
+	if _, err := s.Registry(); err == nil {
+		return SourceTypeRegistry, nil
+	}
+
 	return "", fmt.Errorf("unknown source type: %s", string(*s))
 }
 
+// RegistrySource is a module in a terraform registry
+type RegistrySource struct {
+	Host      string
+	Namespace string
+	Name      string
+	Provider  string
+	// Subdir is the path of a submodule within the module, if any
+	Subdir string
+}
+
+// Registry parses the source as a terraform registry module. Sources without a hostname refer to the public
+// registry.
+func (s *Source) Registry() (*RegistrySource, error) {
+	m := registrySourcePattern.FindStringSubmatch(string(*s))
+	if m == nil {
+		return nil, fmt.Errorf("not a registry source: %s", string(*s))
+	}
+
+	host := m[1]
+	if host == "" {
+		host = DefaultRegistryHost
+	} else if !strings.Contains(host, ".") && !strings.Contains(host, ":") {
+		// The first segment is not a hostname, so there are too many segments for a registry source
+		return nil, fmt.Errorf("not a registry source: %s", string(*s))
+	}
+
+	return &RegistrySource{
+		Host:      strings.ToLower(host),
+		Namespace: m[2],
+		Name:      m[3],
+		Provider:  m[4],
+		Subdir:    m[5],
+	}, nil
+}
+
 func (s *Source) GetVersionSource(version string) (string, error) {
 	t, err := s.Type()
 	if err != nil {
@@ -93,6 +142,9 @@ func (s *Source) GetVersionSource(version string) (string, error) {
 	case SourceTypeGCS:
 		// For GCS and AWS we assume that the version is the name of the zip file
 		return fmt.Sprintf("%s/%s.zip", string(*s), version), nil
+	case SourceTypeRegistry:
+		// Registry modules get their version through a separate `version` argument of the module block
+		return string(*s), nil
 	}
 
 	return "", fmt.Errorf("unsupported source type: %s", t)
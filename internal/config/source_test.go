@@ -23,7 +23,13 @@ func TestType(t *testing.T) {
 		{source: Source("https://example.com/vpc.zip"), expected: SourceTypeHttp},
 		{source: Source("s3::https://github.com/my/project"), expected: SourceTypeS3},
 		{source: Source("gcs::https://github.com/my/project"), expected: SourceTypeGCS},
+		{source: Source("terraform-aws-modules/vpc/aws"), expected: SourceTypeRegistry},
+		{source: Source("terraform-aws-modules/iam/aws//modules/iam-user"), expected: SourceTypeRegistry},
+		{source: Source("app.terraform.io/example-corp/k8s-cluster/azurerm"), expected: SourceTypeRegistry},
+		{source: Source("localhost:8080/example-corp/k8s-cluster/azurerm"), expected: SourceTypeRegistry},
 		{source: Source("bla::https://github.com/my/project"), error: true},
+		{source: Source("local/example-corp/k8s-cluster/azurerm"), error: true},
+		{source: Source("my/project"), error: true},
 	}
 	for _, tc := range tests {
 		t.Run(string(tc.source), func(t *testing.T) {
@@ -57,6 +63,7 @@ func TestGetVersionSource(t *testing.T) {
 		{source: Source("git::https://example.com/vpc.git"), expected: "git::https://example.com/vpc.git?ref=v1.0.0"},
 		{source: Source("s3::https://github.com/my/project"), expected: "s3::https://github.com/my/project/v1.0.0.zip"},
 		{source: Source("gcs::https://github.com/my/project"), expected: "gcs::https://github.com/my/project/v1.0.0.zip"},
+		{source: Source("terraform-aws-modules/vpc/aws"), expected: "terraform-aws-modules/vpc/aws"},
 
 		//Unsupported sources. We throw an error instead, as we don't know how to handle them yet
 		{source: Source("http://example.com/vpc.zip"), error: true},
@@ -89,3 +96,30 @@ func TestIsTypeInvalid(t *testing.T) {
 	s := Source("git::https://example.com/vpc.git")
 	assert.False(t, s.IsType(SourceTypeLocal))
 }
+
+func TestRegistry(t *testing.T) {
+	s := Source("terraform-aws-modules/iam/aws//modules/iam-user")
+	r, err := s.Registry()
+	assert.NoError(t, err)
+	assert.Equal(t, &RegistrySource{
+		Host:      DefaultRegistryHost,
+		Namespace: "terraform-aws-modules",
+		Name:      "iam",
+		Provider:  "aws",
+		Subdir:    "modules/iam-user",
+	}, r)
+
+	s = Source("App.Terraform.io/example-corp/k8s-cluster/azurerm")
+	r, err = s.Registry()
+	assert.NoError(t, err)
+	assert.Equal(t, &RegistrySource{
+		Host:      "app.terraform.io",
+		Namespace: "example-corp",
+		Name:      "k8s-cluster",
+		Provider:  "azurerm",
+	}, r)
+
+	s = Source("./local/file")
+	_, err = s.Registry()
+	assert.Error(t, err)
+}
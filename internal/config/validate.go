@@ -31,6 +31,11 @@ func GenerateSchema(ctx context.Context, filename string, pr *plugins.PluginRepo
 	}
 	defer raw.plugins.Close()
 
+	// The schema should describe every configured plugin, not only the ones currently used
+	if err := raw.plugins.StartAll(ctx); err != nil {
+		return "", err
+	}
+
 	data, err := createFullSchema(raw.plugins, &raw.Global)
 	if err != nil {
 		return "", err
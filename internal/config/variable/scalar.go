@@ -104,7 +104,25 @@ func ModuleTransformFunc() TransformValueFunc {
 	}
 }
 
-func RemoteStateTransformFunc(repository *state.Repository, siteIdentifier string) TransformValueFunc {
+// SeparateOutputsFunc returns whether the component renders a terraform output for each of its outputs, instead of a
+// single output holding all of them
+type SeparateOutputsFunc func(component string) bool
+
+// SeparateOutputName returns the name of the terraform output that holds a single output of a component
+func SeparateOutputName(component, output string) string {
+	return fmt.Sprintf("%s_%s", component, output)
+}
+
+// splitOutputPath splits the path following the component name of a reference into the name of the output and the
+// remainder, which selects a value within the output
+func splitOutputPath(p string) (string, string) {
+	if i := strings.IndexAny(p, ".["); i >= 0 {
+		return p[:i], p[i:]
+	}
+	return p, ""
+}
+
+func RemoteStateTransformFunc(repository *state.Repository, siteIdentifier string, separate SeparateOutputsFunc) TransformValueFunc {
 	return func(value any) (any, error) {
 		val, ok := value.(string)
 		if !ok {
@@ -127,12 +145,45 @@ func RemoteStateTransformFunc(repository *state.Repository, siteIdentifier strin
 			}
 
 			replacement := fmt.Sprintf(`data.terraform_remote_state.%s.outputs.%s.%s`, stateKey, part[1], part[2])
+			if separate != nil && separate(part[1]) {
+				output, rest := splitOutputPath(part[2])
+				replacement = fmt.Sprintf(`data.terraform_remote_state.%s.outputs.%s%s`,
+					stateKey, SeparateOutputName(part[1], output), rest)
+			}
 			val = strings.ReplaceAll(val, strings.Join(part, "."), replacement)
 		}
 		return strings.TrimSpace(val), nil
 	}
 }
 
+// Reference is a `${component.<component>.<output>}` reference to an output of another component
+type Reference struct {
+	Component string
+	Output    string
+}
+
+// ListReferences returns the component outputs that are referenced by the variables
+func (vl *VariablesMap) ListReferences() ([]Reference, error) {
+	var references []Reference
+	_, err := vl.Transform(func(value any) (any, error) {
+		val, ok := value.(string)
+		if !ok {
+			return value, nil
+		}
+
+		parts, err := parseValues(val)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range parts {
+			output, _ := splitOutputPath(part[2])
+			references = append(references, Reference{Component: part[1], Output: output})
+		}
+		return value, nil
+	})
+	return references, err
+}
+
 func MustCreateNewScalarVariable(value any) *ScalarVariable {
 	v, err := NewScalarVariable(value)
 	if err != nil {
@@ -89,13 +89,49 @@ func TestRemoteStateTransformFunc(t *testing.T) {
 		value, err := NewScalarVariable(tc.input)
 		assert.NoError(t, err)
 
-		res, err := value.TransformValue(RemoteStateTransformFunc(r, "test-1"))
+		res, err := value.TransformValue(RemoteStateTransformFunc(r, "test-1", nil))
 		assert.NoError(t, err)
 
 		assert.Equal(t, tc.output, res)
 	}
 }
 
+func TestRemoteStateTransformFuncSeparateOutputs(t *testing.T) {
+	r := state.NewRepository()
+	rr, _ := state.NewRenderer(state.DefaultType, "my_state", nil)
+	assert.NoError(t, r.Add(rr))
+	r.Alias("my_state", "test-1/foo")
+	r.Alias("my_state", "test-1/bar")
+
+	separate := func(component string) bool { return component == "foo" }
+
+	value, err := NewScalarVariable("${component.foo.endpoint.host} ${component.foo.urls[0]} ${component.bar.other}")
+	assert.NoError(t, err)
+
+	res, err := value.TransformValue(RemoteStateTransformFunc(r, "test-1", separate))
+	assert.NoError(t, err)
+	assert.Equal(t, "${data.terraform_remote_state.my_state.outputs.foo_endpoint.host} "+
+		"${data.terraform_remote_state.my_state.outputs.foo_urls[0]} "+
+		"${data.terraform_remote_state.my_state.outputs.bar.other}", res)
+}
+
+func TestListReferences(t *testing.T) {
+	vars := VariablesMap{
+		"endpoint": MustCreateNewScalarVariable("${component.foo.endpoint.host}"),
+		"nested": NewMapVariable(map[string]Variable{
+			"urls": NewSliceVariable([]Variable{MustCreateNewScalarVariable("${component.bar.urls[0]}")}),
+		}),
+		"plain": MustCreateNewScalarVariable("value"),
+	}
+
+	references, err := vars.ListReferences()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []Reference{
+		{Component: "foo", Output: "endpoint"},
+		{Component: "bar", Output: "urls"},
+	}, references)
+}
+
 func TestInterpolateComponentVarsSuccess(t *testing.T) {
 	var testCases = []struct {
 		Description string
@@ -0,0 +1,339 @@
+package formatter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Options configure how config files are formatted
+type Options struct {
+	// SortComponents sorts the components and the components of every site by name
+	SortComponents bool
+}
+
+// section describes the known keys of a mapping in the config, and the sections of their values
+type section struct {
+	// order is the order of the known keys. Unknown keys, like the configuration of plugins, are placed at the position
+	// of `*` in their original order, or at the end when the order has no `*`
+	order []string
+	// fields are the sections of the values of known keys
+	fields map[string]*section
+	// items is the section of every item when the value is a sequence
+	items *section
+	// sortable marks a sequence of items that can be sorted by name
+	sortable bool
+}
+
+var componentSection = &section{
+	order: []string{"name", "source", "version", "branch", "paths", "integrations", "*", "outputs", "terraform",
+		"endpoints", "health_check_path"},
+}
+
+var siteComponentSection = &section{
+	order: []string{"name", "deployment", "depends_on", "*", "variables", "secrets", "store_variables",
+		"store_secrets", "health_check_path", "imports", "moved", "extra_terraform"},
+}
+
+var rootSection = &section{
+	order: []string{"mach_composer", "global", "components", "sites"},
+	fields: map[string]*section{
+		"mach_composer": {
+			order: []string{"version", "variables_file", "cloud", "deployment", "plugin_registry", "plugin_mirror",
+				"templates_dir", "plugins", "terraform", "policies"},
+		},
+		"global": {
+			order: []string{"environment", "terraform_config", "cloud", "*", "variables", "secrets",
+				"extra_terraform"},
+		},
+		"components": {items: componentSection, sortable: true},
+		"sites": {
+			items: &section{
+				order: []string{"identifier", "endpoints", "deployment", "approval", "*", "variables", "secrets",
+					"components", "imports", "moved", "extra_terraform"},
+				fields: map[string]*section{
+					"components": {items: siteComponentSection, sortable: true},
+				},
+			},
+		},
+	},
+}
+
+// Format formats the yaml documents of a config file. Comments are kept, the known keys are put in a consistent
+// order and everything is indented with two spaces. References to other files, with `$ref` or `${include()}`, are
+// kept as they are.
+func Format(src []byte, opts *Options) ([]byte, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	var docs []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(src))
+	for {
+		doc := &yaml.Node{}
+		err := decoder.Decode(doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) == 0 {
+		return src, nil
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	for _, doc := range docs {
+		if isEncrypted(doc) {
+			return nil, errors.New("files encrypted with sops cannot be formatted")
+		}
+
+		keepHeader(doc)
+		formatNode(doc, rootSection, opts)
+		keepMergeKeys(doc)
+		if err := encoder.Encode(doc); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := verify(buf.Bytes()); err != nil {
+		return nil, fmt.Errorf("formatting results in an invalid file: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// verify checks that all yaml documents of the formatted file can be loaded, so a file is never replaced by one that
+// cannot be loaded anymore
+func verify(result []byte) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(result))
+	for {
+		var doc any
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// isEncrypted returns true for documents encrypted with sops, as reordering them invalidates their checksum
+func isEncrypted(doc *yaml.Node) bool {
+	if len(doc.Content) == 0 {
+		return false
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i < len(root.Content); i += 2 {
+		if root.Content[i].Value == "sops" {
+			return true
+		}
+	}
+	return false
+}
+
+// keepHeader moves a comment at the start of the document, which is parsed as the comment of the first key, to the
+// document so it stays at the top when the keys are reordered
+func keepHeader(doc *yaml.Node) {
+	if len(doc.Content) == 0 || doc.HeadComment != "" {
+		return
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode || len(root.Content) == 0 {
+		return
+	}
+	doc.HeadComment = root.Content[0].HeadComment
+	root.Content[0].HeadComment = ""
+}
+
+// keepMergeKeys removes the tag of merge keys, which the encoder would otherwise write as `!!merge <<`
+func keepMergeKeys(doc *yaml.Node) {
+	walk(doc, func(n *yaml.Node) {
+		if n.Kind == yaml.ScalarNode && n.Tag == "!!merge" {
+			n.Tag = ""
+		}
+	})
+}
+
+func formatNode(node *yaml.Node, s *section, opts *Options) {
+	if s == nil {
+		return
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			formatNode(child, s, opts)
+		}
+	case yaml.MappingNode:
+		if isReference(node) {
+			return
+		}
+		sortKeys(node, s.order)
+		for i := 0; i < len(node.Content); i += 2 {
+			formatNode(node.Content[i+1], s.fields[node.Content[i].Value], opts)
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			formatNode(item, s.items, opts)
+		}
+		if s.sortable && opts.SortComponents {
+			sortByName(node)
+		}
+	}
+}
+
+// isReference returns true for a `$ref` to (a part of) another file, which is kept as it is
+func isReference(node *yaml.Node) bool {
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == "$ref" {
+			return true
+		}
+	}
+	return false
+}
+
+// sortKeys orders the key value pairs of the mapping according to the given order
+func sortKeys(node *yaml.Node, order []string) {
+	rank := map[string]int{}
+	unknown := len(order)
+	for i, key := range order {
+		if key == "*" {
+			unknown = i
+		}
+		rank[key] = i
+	}
+
+	pairs := make([][2]*yaml.Node, 0, len(node.Content)/2)
+	for i := 0; i < len(node.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{node.Content[i], node.Content[i+1]})
+	}
+
+	position := func(key *yaml.Node) int {
+		if r, ok := rank[key.Value]; ok && key.Value != "*" {
+			return r
+		}
+		return unknown
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return position(pairs[i][0]) < position(pairs[j][0])
+	})
+
+	content := make([]*yaml.Node, 0, len(node.Content))
+	for _, pair := range pairs {
+		content = append(content, pair[0], pair[1])
+	}
+	if anchorsFirst(content) {
+		node.Content = content
+	}
+}
+
+// sortByName sorts the items of the sequence by their name. Sequences with items without a name, like references to
+// other files, are kept in their original order.
+func sortByName(node *yaml.Node) {
+	names := make(map[*yaml.Node]string, len(node.Content))
+	for _, item := range node.Content {
+		name, ok := itemName(item)
+		if !ok {
+			return
+		}
+		names[item] = name
+	}
+
+	sorted := slices.Clone(node.Content)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return names[sorted[i]] < names[sorted[j]]
+	})
+
+	if anchorsFirst(sorted) {
+		node.Content = sorted
+	}
+}
+
+// anchorsFirst returns whether every alias in the given nodes comes after its anchor, when the anchor is defined in one
+// of the nodes. Reordering the keys or items of a node is only possible when this holds, as a yaml alias cannot refer
+// to an anchor that follows it.
+func anchorsFirst(nodes []*yaml.Node) bool {
+	local := map[*yaml.Node]bool{}
+	for _, n := range nodes {
+		walk(n, func(n *yaml.Node) {
+			if n.Anchor != "" {
+				local[n] = true
+			}
+		})
+	}
+
+	defined := map[*yaml.Node]bool{}
+	valid := true
+	for _, n := range nodes {
+		walk(n, func(n *yaml.Node) {
+			if n.Kind == yaml.AliasNode && local[n.Alias] && !defined[n.Alias] {
+				valid = false
+			}
+			if n.Anchor != "" {
+				defined[n] = true
+			}
+		})
+	}
+	return valid
+}
+
+// walk calls fn for the node and all its descendants, in document order
+func walk(node *yaml.Node, fn func(*yaml.Node)) {
+	fn(node)
+	for _, child := range node.Content {
+		walk(child, fn)
+	}
+}
+
+func itemName(node *yaml.Node) (string, bool) {
+	if node.Kind != yaml.MappingNode {
+		return "", false
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == "name" && node.Content[i+1].Kind == yaml.ScalarNode {
+			return node.Content[i+1].Value, true
+		}
+	}
+	return "", false
+}
+
+// FormatFile formats the file and returns whether its content changed. With check set the file is not written.
+func FormatFile(filename string, opts *Options, check bool) (bool, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return false, err
+	}
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := Format(src, opts)
+	if err != nil {
+		return false, fmt.Errorf("failed to format %s: %w", filename, err)
+	}
+	if bytes.Equal(src, result) {
+		return false, nil
+	}
+	if check {
+		return true, nil
+	}
+	return true, os.WriteFile(filename, result, info.Mode().Perm())
+}
@@ -0,0 +1,199 @@
+package formatter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const unformatted = `# yaml-language-server: $schema=schema.json
+sites:
+    - identifier: my-site # the site
+      components:
+          - name: payment
+            variables:
+                foo: bar
+          - name: api
+            deployment:
+                type: site-component
+      commercetools:
+          project_key: my-site
+components: ${include(components.yml)}
+global:
+    variables:
+        a: "quoted"
+    environment: test
+    # the cloud provider
+    cloud: aws
+mach_composer:
+    plugins:
+        aws:
+            source: mach-composer/aws
+            version: 0.1.0
+    version: 1
+`
+
+func TestFormat(t *testing.T) {
+	result, err := Format([]byte(unformatted), nil)
+	require.NoError(t, err)
+	assert.Equal(t, `# yaml-language-server: $schema=schema.json
+
+mach_composer:
+  version: 1
+  plugins:
+    aws:
+      source: mach-composer/aws
+      version: 0.1.0
+global:
+  environment: test
+  # the cloud provider
+  cloud: aws
+  variables:
+    a: "quoted"
+components: ${include(components.yml)}
+sites:
+  - identifier: my-site # the site
+    commercetools:
+      project_key: my-site
+    components:
+      - name: payment
+        variables:
+          foo: bar
+      - name: api
+        deployment:
+          type: site-component
+`, string(result))
+
+	again, err := Format(result, nil)
+	require.NoError(t, err)
+	assert.Equal(t, string(result), string(again))
+}
+
+func TestFormatSortComponents(t *testing.T) {
+	result, err := Format([]byte(`components:
+  - source: ./payment
+    name: payment
+  - name: api
+    source: ./api
+sites:
+  - identifier: my-site
+    components:
+      - name: payment
+      - $ref: "site-components.yml#/api"
+`), &Options{SortComponents: true})
+	require.NoError(t, err)
+	// Site components that reference another file have no name, so they are not sorted
+	assert.Equal(t, `components:
+  - name: api
+    source: ./api
+  - name: payment
+    source: ./payment
+sites:
+  - identifier: my-site
+    components:
+      - name: payment
+      - $ref: "site-components.yml#/api"
+`, string(result))
+}
+
+func TestFormatKeepsReferences(t *testing.T) {
+	src := `components:
+  $ref: "components.yml#/components"
+sites:
+  - $ref: "sites.yml"
+    identifier: my-site
+`
+	result, err := Format([]byte(src), &Options{SortComponents: true})
+	require.NoError(t, err)
+	assert.Equal(t, src, string(result))
+}
+
+func TestFormatKeepsAnchorsBeforeAliases(t *testing.T) {
+	// Unknown root keys are placed at the end, which would move the anchor after the components using it
+	src := `x-defaults: &defaults
+  source: ./shared
+components:
+  - name: api
+    <<: *defaults
+`
+	result, err := Format([]byte(src), nil)
+	require.NoError(t, err)
+	assert.Equal(t, src, string(result))
+
+	// Sorting the components by name would move the anchored zeta after alpha, which refers to it
+	result, err = Format([]byte(`components:
+  - &zeta
+    name: zeta
+    source: ./zeta
+  - <<: *zeta
+    name: alpha
+`), &Options{SortComponents: true})
+	require.NoError(t, err)
+	assert.Equal(t, `components:
+  - &zeta
+    name: zeta
+    source: ./zeta
+  - name: alpha
+    <<: *zeta
+`, string(result))
+
+	// Anchors that are not moved after their aliases do not prevent sorting
+	result, err = Format([]byte(`components:
+  - name: zeta
+    source: &source ./shared
+  - name: alpha
+    source: ./alpha
+sites:
+  - identifier: my-site
+    components:
+      - name: zeta
+        variables:
+          source: *source
+`), &Options{SortComponents: true})
+	require.NoError(t, err)
+	assert.Equal(t, `components:
+  - name: alpha
+    source: ./alpha
+  - name: zeta
+    source: &source ./shared
+sites:
+  - identifier: my-site
+    components:
+      - name: zeta
+        variables:
+          source: *source
+`, string(result))
+}
+
+func TestVerify(t *testing.T) {
+	assert.NoError(t, verify([]byte("a: &a 1\nb: *a\n---\nc: 2\n")))
+	assert.ErrorContains(t, verify([]byte("b: *a\na: &a 1\n")), "unknown anchor 'a'")
+}
+
+func TestFormatEncrypted(t *testing.T) {
+	_, err := Format([]byte("secret: ENC[AES256_GCM,data:abc]\nsops:\n  version: 3.7.3\n"), nil)
+	assert.EqualError(t, err, "files encrypted with sops cannot be formatted")
+}
+
+func TestFormatFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "main.yml")
+	require.NoError(t, os.WriteFile(filename, []byte(unformatted), 0600))
+
+	changed, err := FormatFile(filename, nil, true)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	content, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, unformatted, string(content))
+
+	changed, err = FormatFile(filename, nil, false)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	changed, err = FormatFile(filename, nil, true)
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
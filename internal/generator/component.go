@@ -3,82 +3,81 @@ package generator
 import (
 	"context"
 	"fmt"
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
 	"github.com/mach-composer/mach-composer-cli/internal/config"
 	"github.com/mach-composer/mach-composer-cli/internal/config/variable"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
-	"github.com/mach-composer/mach-composer-cli/internal/utils"
 	"runtime"
 	"slices"
 	"strings"
 )
 
-type componentContext struct {
-	ComponentName       string
-	ComponentVersion    string
-	ComponentHash       string
-	ComponentVariables  string
-	ComponentSecrets    string
-	SiteName            string
-	Environment         string
-	Source              string
-	PluginResources     []string
-	PluginProviders     []string
-	PluginDependsOn     []string
-	PluginVariables     []string
-	HasCloudIntegration bool
-}
+// hclStringEscaper escapes text so it can be used within a quoted terraform string
+var hclStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "${", "$${", "%{", "%%{")
 
-func renderSiteComponent(ctx context.Context, cfg *config.MachConfig, n *graph.SiteComponent) (string, error) {
-	result := []string{
-		"# This file is auto-generated by MACH composer",
-		fmt.Sprintf("# SiteComponent: %s", n.Identifier()),
-	}
+func renderSiteComponent(ctx context.Context, cfg *config.MachConfig, n *graph.SiteComponent) (*terraformFiles, error) {
+	result := newTerraformFiles(fmt.Sprintf("# SiteComponent: %s", n.Identifier()))
 
 	// Render the terraform config
-	val, err := renderSiteComponentTerraformConfig(cfg, n)
+	val, err := renderSiteComponentTerraformConfig(ctx, cfg, n)
 	if err != nil {
-		return "", fmt.Errorf("renderSiteTerraformConfig: %w", err)
+		return nil, fmt.Errorf("renderSiteTerraformConfig: %w", err)
+	}
+	if err := result.addTerraformConfig(val); err != nil {
+		return nil, fmt.Errorf("renderSiteTerraformConfig: %w", err)
 	}
-	result = append(result, val)
 
 	// Render all the file sources
-	val, err = renderFileSources(cfg, n.SiteConfig)
+	val, err = renderFileSources(ctx, cfg, n.SiteConfig)
 	if err != nil {
-		return "", fmt.Errorf("failed to render file sources: %w", err)
+		return nil, fmt.Errorf("failed to render file sources: %w", err)
+	}
+	if err := result.add(fileSourcesFile, val); err != nil {
+		return nil, fmt.Errorf("failed to render file sources: %w", err)
 	}
-	result = append(result, val)
 
 	// Render all the resources required by the site siteComponent
-	val, err = renderSiteComponentResources(cfg, n)
+	val, err = renderSiteComponentResources(ctx, cfg, n)
 	if err != nil {
-		return "", fmt.Errorf("failed to render resources: %w", err)
+		return nil, fmt.Errorf("failed to render resources: %w", err)
+	}
+	if err := result.addResources(val); err != nil {
+		return nil, fmt.Errorf("failed to render resources: %w", err)
 	}
-	result = append(result, val)
 
 	// Render data links to other deployments
 	val, err = renderRemoteSources(cfg, n)
 	if err != nil {
-		return "", fmt.Errorf("failed to render remote sources: %w", err)
+		return nil, fmt.Errorf("failed to render remote sources: %w", err)
+	}
+	if err := result.add(remoteStateFile, val); err != nil {
+		return nil, fmt.Errorf("failed to render remote sources: %w", err)
 	}
-	result = append(result, val)
 
 	// Render the siteComponent module
 	val, err = renderComponentModule(ctx, cfg, n)
 	if err != nil {
-		return "", fmt.Errorf("failed to render component: %w", err)
+		return nil, fmt.Errorf("failed to render component: %w", err)
+	}
+	if err := result.add(componentFile(n), val); err != nil {
+		return nil, fmt.Errorf("failed to render component: %w", err)
 	}
-	result = append(result, val)
 
-	return strings.Join(result, "\n"), nil
+	if err := result.checkProviders(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
-// renderSiteComponentTerraformConfig uses templates/terraform.tmpl to generate a terraform snippet for each component
-func renderSiteComponentTerraformConfig(cfg *config.MachConfig, n graph.Node) (string, error) {
-	tpl, err := templates.ReadFile("templates/terraform.tmpl")
-	if err != nil {
-		return "", err
-	}
+// componentFile returns the name of the file the module of the site component is written to. The name is prefixed,
+// so it cannot collide with the other generated files.
+func componentFile(n *graph.SiteComponent) string {
+	return "component_" + n.SiteComponentConfig.Name + ".tf"
+}
 
+// renderSiteComponentTerraformConfig uses templates/terraform.tmpl to generate a terraform snippet for each component
+func renderSiteComponentTerraformConfig(ctx context.Context, cfg *config.MachConfig, n graph.Node) (string, error) {
 	site := n.(*graph.SiteComponent).SiteConfig
 	siteComponent := n.(*graph.SiteComponent).SiteComponentConfig
 
@@ -103,25 +102,20 @@ func renderSiteComponentTerraformConfig(cfg *config.MachConfig, n graph.Node) (s
 		return "", err
 	}
 
-	templateContext := struct {
-		Providers     []string
-		BackendConfig string
-		IncludeSOPS   bool
-	}{
+	tc := terraformContext{
 		Providers:     providers,
 		BackendConfig: bc,
 		IncludeSOPS:   cfg.Variables.HasEncrypted(site.Identifier),
 	}
-	return utils.RenderGoTemplate(string(tpl), templateContext)
-}
-
-// renderSiteComponentResources uses templates/resources.tmpl to generate a terraform snippet for each component
-func renderSiteComponentResources(cfg *config.MachConfig, n *graph.SiteComponent) (string, error) {
-	tpl, err := templates.ReadFile("templates/resources.tmpl")
-	if err != nil {
+	if err := mergeComponentTerraform(&tc, []*config.ComponentConfig{siteComponent.Definition}); err != nil {
 		return "", err
 	}
 
+	return renderTemplate(ctx, cfg, "terraform.tmpl", tc)
+}
+
+// renderSiteComponentResources uses templates/resources.tmpl to generate a terraform snippet for each component
+func renderSiteComponentResources(ctx context.Context, cfg *config.MachConfig, n *graph.SiteComponent) (string, error) {
 	var resources []string
 	for _, plugin := range cfg.Plugins.Names(n.SiteComponentConfig.Definition.Integrations...) {
 		content, err := plugin.RenderTerraformResources(n.SiteConfig.Identifier)
@@ -134,16 +128,11 @@ func renderSiteComponentResources(cfg *config.MachConfig, n *graph.SiteComponent
 		}
 	}
 
-	return utils.RenderGoTemplate(string(tpl), resources)
+	return renderTemplate(ctx, cfg, "resources.tmpl", resources)
 }
 
 // renderComponentModule uses templates/component.tmpl to generate a terraform snippet for each component
-func renderComponentModule(_ context.Context, cfg *config.MachConfig, n *graph.SiteComponent) (string, error) {
-	tpl, err := templates.ReadFile("templates/site_component.tmpl")
-	if err != nil {
-		return "", err
-	}
-
+func renderComponentModule(ctx context.Context, cfg *config.MachConfig, n *graph.SiteComponent) (string, error) {
 	tc := componentContext{
 		ComponentName:    n.SiteComponentConfig.Name,
 		ComponentVersion: n.SiteComponentConfig.Definition.Version,
@@ -156,7 +145,7 @@ func renderComponentModule(_ context.Context, cfg *config.MachConfig, n *graph.S
 	}
 
 	for _, plugin := range cfg.Plugins.Names(n.SiteComponentConfig.Definition.Integrations...) {
-		plugin, err := cfg.Plugins.Get(plugin.Name)
+		plugin, err := cfg.Plugins.Get(ctx, plugin.Name)
 		if err != nil {
 			return "", err
 		}
@@ -176,6 +165,19 @@ func renderComponentModule(_ context.Context, cfg *config.MachConfig, n *graph.S
 		tc.PluginDependsOn = append(tc.PluginDependsOn, cr.DependsOn...)
 	}
 
+	for _, o := range n.SiteComponentConfig.Definition.Outputs {
+		description := o.Description
+		if description == "" {
+			description = fmt.Sprintf("The %s output of %s", o.Name, n.SiteComponentConfig.Name)
+		}
+		tc.Outputs = append(tc.Outputs, componentOutput{
+			Name:         variable.SeparateOutputName(n.SiteComponentConfig.Name, o.Name),
+			ModuleOutput: o.Name,
+			Description:  hclStringEscaper.Replace(description),
+			Sensitive:    o.Sensitive,
+		})
+	}
+
 	if n.SiteComponentConfig.HasCloudIntegration(&cfg.Global) {
 		tc.HasCloudIntegration = true
 		tc.ComponentVariables = "variables = {}"
@@ -184,7 +186,7 @@ func renderComponentModule(_ context.Context, cfg *config.MachConfig, n *graph.S
 
 	var variablesMap = variable.MergeVariablesMaps(n.ProjectConfig.Global.Variables, n.SiteConfig.Variables, n.SiteComponentConfig.Variables)
 	if len(variablesMap) > 0 {
-		val, err := serializeToHCL("variables", variablesMap, n.SiteComponentConfig.Deployment.Type, cfg.StateRepository, n.SiteConfig.Identifier)
+		val, err := serializeToHCL("variables", variablesMap, n.SiteComponentConfig.Deployment.Type, cfg.StateRepository, n.SiteConfig.Identifier, separateOutputs(n.SiteConfig))
 		if err != nil {
 			return "", err
 		}
@@ -193,7 +195,7 @@ func renderComponentModule(_ context.Context, cfg *config.MachConfig, n *graph.S
 
 	var secretsMap = variable.MergeVariablesMaps(n.ProjectConfig.Global.Secrets, n.SiteConfig.Secrets, n.SiteComponentConfig.Secrets)
 	if len(secretsMap) > 0 {
-		val, err := serializeToHCL("secrets", secretsMap, n.SiteComponentConfig.Deployment.Type, cfg.StateRepository, n.SiteConfig.Identifier)
+		val, err := serializeToHCL("secrets", secretsMap, n.SiteComponentConfig.Deployment.Type, cfg.StateRepository, n.SiteConfig.Identifier, separateOutputs(n.SiteConfig))
 		if err != nil {
 			return "", err
 		}
@@ -212,13 +214,104 @@ func renderComponentModule(_ context.Context, cfg *config.MachConfig, n *graph.S
 		tc.Source = vs
 	}
 
-	val, err := utils.RenderGoTemplate(string(tpl), tc)
+	if n.SiteComponentConfig.Definition.Source.IsType(config.SourceTypeRegistry) {
+		tc.ModuleVersion = hclStringEscaper.Replace(n.SiteComponentConfig.Definition.Version)
+	}
+
+	val, err := renderTemplate(ctx, cfg, "site_component.tmpl", tc)
 	if err != nil {
 		return "", fmt.Errorf("failed rendering site component: %w", err)
 	}
+
+	refactoring, err := renderRefactoringBlocks(ctx, cfg, n.SiteComponentConfig.Name, n.SiteComponentConfig.Imports, n.SiteComponentConfig.Moved)
+	if err != nil {
+		return "", fmt.Errorf("failed rendering site component import and moved blocks: %w", err)
+	}
+	if refactoring != "" {
+		val = strings.Join([]string{val, refactoring}, "\n")
+	}
+
 	return val, nil
 }
 
+// separateOutputs returns whether a component of the site renders a terraform output for each of its outputs, which is
+// the case when the component declares its outputs
+func separateOutputs(site config.SiteConfig) variable.SeparateOutputsFunc {
+	return func(component string) bool {
+		c, err := site.Components.Get(component)
+		return err == nil && c.Definition != nil && len(c.Definition.Outputs) > 0
+	}
+}
+
+// validateOutputReferences checks that the component outputs referenced by the variables and secrets of the site
+// components are declared, for the referenced components that declare their outputs
+func validateOutputReferences(cfg *config.MachConfig) error {
+	var errs []error
+	for _, site := range cfg.Sites {
+		for _, component := range site.Components {
+			variables, err := component.Variables.ListReferences()
+			if err != nil {
+				return err
+			}
+			secrets, err := component.Secrets.ListReferences()
+			if err != nil {
+				return err
+			}
+
+			for _, ref := range append(variables, secrets...) {
+				referenced, err := site.Components.Get(ref.Component)
+				if err != nil || referenced.Definition == nil || len(referenced.Definition.Outputs) == 0 {
+					continue
+				}
+				if !referenced.Definition.HasOutput(ref.Output) {
+					errs = append(errs, fmt.Errorf("component %s of site %s references output %s of %s, "+
+						"which is not declared in the outputs of component %s", component.Name, site.Identifier,
+						ref.Output, ref.Component, referenced.Definition.Name))
+				}
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return cli.NewGroupedError("invalid component output references", errs)
+	}
+	return nil
+}
+
+// validateOutputNames checks that the terraform outputs rendered for the components of a site have unique names. The
+// name of a declared output is prefixed with the name of its component, so component a_b with output c and component
+// a with output b_c would both render output a_b_c.
+func validateOutputNames(cfg *config.MachConfig) error {
+	var errs []error
+	for _, site := range cfg.Sites {
+		owners := map[string]string{}
+		add := func(name, owner string) {
+			if other, ok := owners[name]; ok {
+				errs = append(errs, fmt.Errorf("%s and %s of site %s both render terraform output %s, rename one "+
+					"of them", other, owner, site.Identifier, name))
+				return
+			}
+			owners[name] = owner
+		}
+
+		for _, component := range site.Components {
+			if component.Definition == nil || len(component.Definition.Outputs) == 0 {
+				add(component.Name, fmt.Sprintf("the outputs of component %s", component.Name))
+				continue
+			}
+			for _, o := range component.Definition.Outputs {
+				add(variable.SeparateOutputName(component.Name, o.Name),
+					fmt.Sprintf("output %s of component %s", o.Name, component.Name))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return cli.NewGroupedError("duplicate component output names", errs)
+	}
+	return nil
+}
+
 // renderRemoteSources uses the state repository to generate a terraform remote_state snippet for each referenced component
 func renderRemoteSources(cfg *config.MachConfig, n *graph.SiteComponent) (string, error) {
 	siteComponentConfig := n.SiteComponentConfig
@@ -0,0 +1,106 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+)
+
+// mergeComponentTerraform merges the terraform settings of the given components into the context of terraform.tmpl.
+// A provider that is already required by a plugin, or by another component with a different source or version, is a
+// conflict.
+func mergeComponentTerraform(tc *terraformContext, components []*config.ComponentConfig) error {
+	names, err := requiredProviderNames(tc.Providers)
+	if err != nil {
+		return err
+	}
+
+	owners := map[string]string{}
+	for _, name := range names {
+		owners[name] = "a plugin"
+	}
+	if tc.IncludeSOPS {
+		owners["sops"] = "the encrypted variables"
+	}
+
+	required := map[string]config.RequiredProvider{}
+	var versions []string
+	for _, c := range components {
+		if c == nil || c.Terraform == nil {
+			continue
+		}
+
+		if v := c.Terraform.RequiredVersion; v != "" && !slices.Contains(versions, v) {
+			versions = append(versions, v)
+		}
+
+		providers := make([]string, 0, len(c.Terraform.RequiredProviders))
+		for name := range c.Terraform.RequiredProviders {
+			providers = append(providers, name)
+		}
+		sort.Strings(providers)
+
+		for _, name := range providers {
+			p := c.Terraform.RequiredProviders[name]
+			if owner, ok := owners[name]; ok {
+				if previous, ok := required[name]; ok && previous == p {
+					continue
+				}
+				return fmt.Errorf("component %s requires provider %s, which is already required by %s", c.Name, name,
+					owner)
+			}
+
+			owners[name] = "component " + c.Name
+			required[name] = p
+			tc.Providers = append(tc.Providers, renderRequiredProvider(name, p))
+		}
+
+		if c.Terraform.Providers != "" {
+			tc.ProviderConfigs = append(tc.ProviderConfigs, c.Terraform.Providers)
+		}
+	}
+
+	tc.RequiredVersion = hclStringEscaper.Replace(strings.Join(versions, ", "))
+	return nil
+}
+
+// requiredProviderNames returns the names of the providers in the given required_providers entries
+func requiredProviderNames(providers []string) ([]string, error) {
+	src := "required_providers {\n" + strings.Join(providers, "\n") + "\n}\n"
+	file, diags := hclsyntax.ParseConfig([]byte(src), "providers.tf", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("the providers rendered by the plugins are invalid: %w", diags)
+	}
+
+	var names []string
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		for name := range block.Body.Attributes {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// renderRequiredProvider renders the required_providers entry of the provider
+func renderRequiredProvider(name string, p config.RequiredProvider) string {
+	attrs := map[string]cty.Value{}
+	if p.Source != "" {
+		attrs["source"] = cty.StringVal(p.Source)
+	}
+	if p.Version != "" {
+		attrs["version"] = cty.StringVal(p.Version)
+	}
+
+	f := hclwrite.NewEmptyFile()
+	f.Body().SetAttributeValue(name, cty.ObjectVal(attrs))
+	return string(f.Bytes())
+}
@@ -0,0 +1,119 @@
+package generator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+)
+
+func TestMergeComponentTerraform(t *testing.T) {
+	random := config.RequiredProvider{Source: "hashicorp/random", Version: "~> 3.0"}
+	components := []*config.ComponentConfig{
+		{
+			Name: "api",
+			Terraform: &config.ComponentTerraform{
+				RequiredVersion:   ">= 1.5",
+				RequiredProviders: map[string]config.RequiredProvider{"random": random, "null": {}},
+				Providers:         "provider \"random\" {}\n",
+			},
+		},
+		{
+			Name: "frontend",
+			Terraform: &config.ComponentTerraform{
+				RequiredVersion:   "< 2.0",
+				RequiredProviders: map[string]config.RequiredProvider{"random": random},
+			},
+		},
+		{Name: "without-settings"},
+		nil,
+	}
+
+	tc := terraformContext{Providers: []string{"aws = {\n  source = \"hashicorp/aws\"\n}"}}
+	require.NoError(t, mergeComponentTerraform(&tc, components))
+	assert.Equal(t, ">= 1.5, < 2.0", tc.RequiredVersion)
+	assert.Equal(t, []string{"provider \"random\" {}\n"}, tc.ProviderConfigs)
+
+	tc.BackendConfig = "backend \"local\" {}"
+	val, err := renderTemplate(context.Background(), &config.MachConfig{}, "terraform.tmpl", tc)
+	require.NoError(t, err)
+
+	files := newTerraformFiles()
+	require.NoError(t, files.addTerraformConfig(val))
+	require.NoError(t, files.checkProviders())
+	assert.Equal(t, `# This file is auto-generated by MACH composer
+terraform {
+  required_version = ">= 1.5, < 2.0"
+  required_providers {
+    aws = {
+      source = "hashicorp/aws"
+    }
+    null = {}
+    random = {
+      source  = "hashicorp/random"
+      version = "~> 3.0"
+    }
+  }
+}
+
+provider "random" {}`, string(files.render()[providersFile]))
+}
+
+func TestMergeComponentTerraformConflicts(t *testing.T) {
+	tc := terraformContext{Providers: []string{"aws = {}"}, IncludeSOPS: true}
+	err := mergeComponentTerraform(&tc, []*config.ComponentConfig{{
+		Name: "api",
+		Terraform: &config.ComponentTerraform{
+			RequiredProviders: map[string]config.RequiredProvider{"aws": {Version: "~> 5.0"}},
+		},
+	}})
+	assert.EqualError(t, err, "component api requires provider aws, which is already required by a plugin")
+
+	tc = terraformContext{IncludeSOPS: true}
+	err = mergeComponentTerraform(&tc, []*config.ComponentConfig{{
+		Name: "api",
+		Terraform: &config.ComponentTerraform{
+			RequiredProviders: map[string]config.RequiredProvider{"sops": {}},
+		},
+	}})
+	assert.EqualError(t, err, "component api requires provider sops, which is already required by the encrypted variables")
+
+	tc = terraformContext{}
+	err = mergeComponentTerraform(&tc, []*config.ComponentConfig{
+		{
+			Name: "api",
+			Terraform: &config.ComponentTerraform{
+				RequiredProviders: map[string]config.RequiredProvider{"random": {Version: "~> 3.0"}},
+			},
+		},
+		{
+			Name: "frontend",
+			Terraform: &config.ComponentTerraform{
+				RequiredProviders: map[string]config.RequiredProvider{"random": {Version: "~> 2.0"}},
+			},
+		},
+	})
+	assert.EqualError(t, err, "component frontend requires provider random, which is already required by component api")
+}
+
+func TestCheckProviders(t *testing.T) {
+	files := newTerraformFiles()
+	require.NoError(t, files.addResources(`
+provider "aws" {}
+provider "aws" {
+  alias = "us"
+}
+`))
+	require.NoError(t, files.checkProviders())
+
+	// Identical providers, like the providers of two components that use the same provider, are only configured once
+	require.NoError(t, files.add(providersFile, "provider \"aws\" {\n  alias = \"us\"\n}\n"))
+	require.NoError(t, files.checkProviders())
+	assert.Len(t, files.files[providersFile].Body().Blocks(), 2)
+
+	require.NoError(t, files.add(providersFile, "provider \"aws\" {\n  alias  = \"us\"\n  region = \"us-east-1\"\n}\n"))
+	assert.EqualError(t, files.checkProviders(), "provider aws.us is configured more than once with different settings")
+}
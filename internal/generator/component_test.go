@@ -0,0 +1,167 @@
+package generator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/config/variable"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/plugins"
+)
+
+func TestRenderComponentOutputs(t *testing.T) {
+	val, err := renderTemplate(context.Background(), &config.MachConfig{}, "site_component.tmpl", componentContext{
+		ComponentName: "api",
+		Source:        "./api",
+	})
+	require.NoError(t, err)
+	assert.Contains(t, val, "output \"api\" {")
+	assert.Contains(t, val, "sensitive = true")
+
+	val, err = renderTemplate(context.Background(), &config.MachConfig{}, "site_component.tmpl", componentContext{
+		ComponentName: "api",
+		Source:        "./api",
+		Outputs: []componentOutput{
+			{Name: "api_url", ModuleOutput: "url", Description: "The url of the api", Sensitive: false},
+			{Name: "api_token", ModuleOutput: "token", Description: "The token of the api", Sensitive: true},
+		},
+	})
+	require.NoError(t, err)
+	assert.NotContains(t, val, "output \"api\" {")
+
+	files := newTerraformFiles()
+	require.NoError(t, files.add("component_api.tf", val))
+	rendered := files.render()
+	assert.Contains(t, string(rendered["component_api.tf"]), `output "api_url" {
+  description = "The url of the api"
+  sensitive   = false
+  value       = module.api.url
+}`)
+	assert.Contains(t, string(rendered["component_api.tf"]), `output "api_token" {
+  description = "The token of the api"
+  sensitive   = true
+  value       = module.api.token
+}`)
+}
+
+func TestRenderComponentModuleVersion(t *testing.T) {
+	val, err := renderTemplate(context.Background(), &config.MachConfig{}, "site_component.tmpl", componentContext{
+		ComponentName: "vpc",
+		Source:        "terraform-aws-modules/vpc/aws",
+		ModuleVersion: "~> 5.0",
+	})
+	require.NoError(t, err)
+
+	files := newTerraformFiles()
+	require.NoError(t, files.add("component_vpc.tf", val))
+	assert.Contains(t, string(files.render()["component_vpc.tf"]), `module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "~> 5.0"
+`)
+
+	val, err = renderTemplate(context.Background(), &config.MachConfig{}, "site_component.tmpl", componentContext{
+		ComponentName: "api",
+		Source:        "./api",
+	})
+	require.NoError(t, err)
+	assert.NotContains(t, val, "version =")
+
+	// The version is escaped, so it cannot end the string it is rendered in
+	val, err = renderComponentModule(context.Background(), &config.MachConfig{Plugins: plugins.NewPluginRepository()},
+		&graph.SiteComponent{
+			SiteComponentConfig: config.SiteComponentConfig{
+				Name: "vpc",
+				Definition: &config.ComponentConfig{
+					Name:    "vpc",
+					Source:  "terraform-aws-modules/vpc/aws",
+					Version: `5.0" } resource "null_resource" "x" {`,
+				},
+			},
+		})
+	require.NoError(t, err)
+	files = newTerraformFiles()
+	require.NoError(t, files.add("component_vpc.tf", val))
+	assert.Contains(t, string(files.render()["component_vpc.tf"]), `version = "5.0\" } resource \"null_resource\" \"x\" {"`)
+}
+
+func TestValidateOutputReferences(t *testing.T) {
+	api := &config.ComponentConfig{
+		Name:    "api",
+		Outputs: []config.ComponentOutput{{Name: "url"}, {Name: "token", Sensitive: true}},
+	}
+	legacy := &config.ComponentConfig{Name: "legacy"}
+
+	site := func(value string) *config.MachConfig {
+		return &config.MachConfig{
+			Sites: []config.SiteConfig{
+				{
+					Identifier: "my-site",
+					Components: config.SiteComponentConfigs{
+						{Name: "api", Definition: api},
+						{Name: "legacy", Definition: legacy},
+						{
+							Name:       "frontend",
+							Definition: &config.ComponentConfig{Name: "frontend"},
+							Variables: variable.VariablesMap{
+								"value": variable.MustCreateNewScalarVariable(value),
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	assert.NoError(t, validateOutputReferences(site("${component.api.url}")))
+	assert.NoError(t, validateOutputReferences(site("${component.api.token}")))
+	// Components that do not declare their outputs are not validated
+	assert.NoError(t, validateOutputReferences(site("${component.legacy.anything}")))
+
+	err := validateOutputReferences(site("${component.api.endpoint}"))
+	var grouped *cli.GroupedError
+	require.ErrorAs(t, err, &grouped)
+	require.Len(t, grouped.Errors, 1)
+	assert.EqualError(t, grouped.Errors[0], "component frontend of site my-site references output endpoint of api, "+
+		"which is not declared in the outputs of component api")
+}
+
+func TestValidateOutputNames(t *testing.T) {
+	site := func(components ...config.SiteComponentConfig) *config.MachConfig {
+		return &config.MachConfig{
+			Sites: []config.SiteConfig{{Identifier: "my-site", Components: components}},
+		}
+	}
+	component := func(name string, outputs ...string) config.SiteComponentConfig {
+		definition := &config.ComponentConfig{Name: name}
+		for _, o := range outputs {
+			definition.Outputs = append(definition.Outputs, config.ComponentOutput{Name: o})
+		}
+		return config.SiteComponentConfig{Name: name, Definition: definition}
+	}
+
+	assert.NoError(t, validateOutputNames(site(component("a", "url"), component("b", "url"), component("legacy"))))
+
+	err := validateOutputNames(site(component("a_b", "c"), component("a", "b_c", "url"), component("a_url")))
+	var grouped *cli.GroupedError
+	require.ErrorAs(t, err, &grouped)
+	require.Len(t, grouped.Errors, 2)
+	assert.EqualError(t, grouped.Errors[0], "output c of component a_b and output b_c of component a of site "+
+		"my-site both render terraform output a_b_c, rename one of them")
+	assert.EqualError(t, grouped.Errors[1], "output url of component a and the outputs of component a_url of site "+
+		"my-site both render terraform output a_url, rename one of them")
+}
+
+func TestComponentFile(t *testing.T) {
+	// Components named like the other generated files do not overwrite them
+	for _, name := range []string{"backend", "providers", "resources", "extra_terraform"} {
+		n := &graph.SiteComponent{SiteComponentConfig: config.SiteComponentConfig{Name: name}}
+		file := componentFile(n)
+		assert.Equal(t, "component_"+name+".tf", file)
+		assert.NotContains(t, []string{backendFile, providersFile, resourcesFile, extraTerraformFilename}, file)
+	}
+}
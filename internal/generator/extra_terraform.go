@@ -0,0 +1,62 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclparse"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+)
+
+const extraTerraformFilename = "extra_terraform.tf"
+
+// extraTerraform returns the extra terraform code that is added to the root module of the node. The code configured
+// globally and on the site is added to every root module of the site, the code of a site component to the root module
+// the component is rendered in.
+func extraTerraform(cfg *config.MachConfig, n graph.Node) []config.ExtraTerraform {
+	var result []config.ExtraTerraform
+	result = append(result, cfg.Global.ExtraTerraform...)
+
+	switch n := n.(type) {
+	case *graph.Site:
+		result = append(result, n.SiteConfig.ExtraTerraform...)
+		for _, component := range n.NestedNodes {
+			if component.SiteComponentConfig.Deployment.Type == config.DeploymentSite {
+				result = append(result, component.SiteComponentConfig.ExtraTerraform...)
+			}
+		}
+	case *graph.SiteComponent:
+		result = append(result, n.SiteConfig.ExtraTerraform...)
+		result = append(result, n.SiteComponentConfig.ExtraTerraform...)
+	}
+	return result
+}
+
+// renderExtraTerraform validates the given extra terraform code and merges it into a single file
+func renderExtraTerraform(extras []config.ExtraTerraform) (string, error) {
+	if len(extras) == 0 {
+		return "", nil
+	}
+
+	result := []string{generatedHeader}
+	parser := hclparse.NewParser()
+	for i, e := range extras {
+		content, err := e.Content()
+		if err != nil {
+			return "", fmt.Errorf("failed to read extra terraform %s: %w", e.Name(), err)
+		}
+
+		filename := e.Name()
+		if e.IsInline() {
+			filename = fmt.Sprintf("extra_terraform[%d]", i)
+		}
+		if _, diags := parser.ParseHCL(content, filename); diags.HasErrors() {
+			return "", fmt.Errorf("extra terraform %s is invalid: %w", e.Name(), diags)
+		}
+
+		result = append(result, fmt.Sprintf("\n# Source: %s", filename), strings.TrimSpace(string(content)))
+	}
+	return strings.Join(result, "\n") + "\n", nil
+}
@@ -0,0 +1,78 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+)
+
+func TestRenderExtraTerraformEmpty(t *testing.T) {
+	val, err := renderExtraTerraform(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "", val)
+}
+
+func TestRenderExtraTerraform(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "providers.tf")
+	require.NoError(t, os.WriteFile(file, []byte("provider \"aws\" {\n  alias  = \"us\"\n  region = \"us-east-1\"\n}\n"), 0600))
+
+	val, err := renderExtraTerraform([]config.ExtraTerraform{
+		config.ExtraTerraform(file),
+		"locals {\n  team = \"checkout\"\n}\n",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `# This file is auto-generated by MACH composer
+
+# Source: `+file+`
+provider "aws" {
+  alias  = "us"
+  region = "us-east-1"
+}
+
+# Source: extra_terraform[1]
+locals {
+  team = "checkout"
+}
+`, val)
+}
+
+func TestRenderExtraTerraformInvalid(t *testing.T) {
+	_, err := renderExtraTerraform([]config.ExtraTerraform{"locals {\n  team = \n"})
+	assert.ErrorContains(t, err, "extra terraform inline is invalid")
+
+	_, err = renderExtraTerraform([]config.ExtraTerraform{"does-not-exist.tf"})
+	assert.ErrorContains(t, err, "failed to read extra terraform does-not-exist.tf")
+}
+
+func TestExtraTerraformForNode(t *testing.T) {
+	cfg := &config.MachConfig{
+		Global: config.GlobalConfig{ExtraTerraform: []config.ExtraTerraform{"global.tf"}},
+	}
+	site := config.SiteConfig{Identifier: "my-site", ExtraTerraform: []config.ExtraTerraform{"site.tf"}}
+	nested := &graph.SiteComponent{
+		SiteConfig: site,
+		SiteComponentConfig: config.SiteComponentConfig{
+			Name:           "nested",
+			Deployment:     &config.Deployment{Type: config.DeploymentSite},
+			ExtraTerraform: []config.ExtraTerraform{"nested.tf"},
+		},
+	}
+	separate := &graph.SiteComponent{
+		SiteConfig: site,
+		SiteComponentConfig: config.SiteComponentConfig{
+			Name:           "separate",
+			Deployment:     &config.Deployment{Type: config.DeploymentSiteComponent},
+			ExtraTerraform: []config.ExtraTerraform{"separate.tf"},
+		},
+	}
+	n := &graph.Site{SiteConfig: site, NestedNodes: []*graph.SiteComponent{nested, separate}}
+
+	assert.Equal(t, []config.ExtraTerraform{"global.tf", "site.tf", "nested.tf"}, extraTerraform(cfg, n))
+	assert.Equal(t, []config.ExtraTerraform{"global.tf", "site.tf", "separate.tf"}, extraTerraform(cfg, separate))
+}
@@ -0,0 +1,268 @@
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	generatedHeader = "# This file is auto-generated by MACH composer"
+
+	backendFile     = "backend.tf"
+	providersFile   = "providers.tf"
+	fileSourcesFile = "file_sources.tf"
+	resourcesFile   = "resources.tf"
+	remoteStateFile = "remote_state.tf"
+	refactoringFile = "refactoring.tf"
+)
+
+// terraformFiles collects the blocks of the terraform files that are generated for a node, so every part of the
+// configuration is written to a file of its own
+type terraformFiles struct {
+	header []string
+	files  map[string]*hclwrite.File
+}
+
+func newTerraformFiles(header ...string) *terraformFiles {
+	return &terraformFiles{
+		header: append([]string{generatedHeader}, header...),
+		files:  make(map[string]*hclwrite.File),
+	}
+}
+
+// add adds the blocks of the given terraform code to the file with the given name
+func (f *terraformFiles) add(name, content string) error {
+	src, err := parseBlocks(content)
+	if err != nil {
+		return err
+	}
+	for _, block := range src.Body().Blocks() {
+		appendBlock(f.file(name), block)
+	}
+	return nil
+}
+
+// addTerraformConfig adds the terraform block, moving the backend to backend.tf and the provider requirements to
+// providers.tf
+func (f *terraformFiles) addTerraformConfig(content string) error {
+	src, err := parseBlocks(content)
+	if err != nil {
+		return err
+	}
+
+	for _, block := range src.Body().Blocks() {
+		if block.Type() != "terraform" {
+			appendBlock(f.file(providersFile), block)
+			continue
+		}
+
+		backend := hclwrite.NewBlock("terraform", nil)
+		for _, child := range block.Body().Blocks() {
+			if child.Type() == "backend" || child.Type() == "cloud" {
+				block.Body().RemoveBlock(child)
+				backend.Body().AppendBlock(child)
+			}
+		}
+		if len(backend.Body().Blocks()) > 0 {
+			appendBlock(f.file(backendFile), backend)
+		}
+		appendBlock(f.file(providersFile), block)
+	}
+	return nil
+}
+
+// addResources adds the resources rendered by the plugins, moving the provider configuration to providers.tf
+func (f *terraformFiles) addResources(content string) error {
+	src, err := parseBlocks(content)
+	if err != nil {
+		return err
+	}
+
+	for _, block := range src.Body().Blocks() {
+		if block.Type() == "provider" {
+			appendBlock(f.file(providersFile), block)
+		} else {
+			appendBlock(f.file(resourcesFile), block)
+		}
+	}
+	return nil
+}
+
+// checkProviders removes providers that are configured more than once with the same settings, for example by two
+// components deployed in the same site, and returns an error when a provider is configured more than once with the
+// same alias but different settings, for example by a plugin and the terraform settings of a component
+func (f *terraformFiles) checkProviders() error {
+	file := f.files[providersFile]
+	if file == nil {
+		return nil
+	}
+
+	seen := map[string][]byte{}
+	for _, block := range file.Body().Blocks() {
+		if block.Type() != "provider" || len(block.Labels()) == 0 {
+			continue
+		}
+
+		key := block.Labels()[0]
+		if alias := block.Body().GetAttribute("alias"); alias != nil {
+			value := strings.TrimSpace(string(alias.Expr().BuildTokens(nil).Bytes()))
+			key += "." + strings.Trim(value, `"`)
+		}
+
+		content := bytes.TrimSpace(hclwrite.Format(block.BuildTokens(nil).Bytes()))
+		if previous, ok := seen[key]; ok {
+			if !bytes.Equal(previous, content) {
+				return fmt.Errorf("provider %s is configured more than once with different settings", key)
+			}
+			file.Body().RemoveBlock(block)
+			continue
+		}
+		seen[key] = content
+	}
+	return nil
+}
+
+// appendBlock appends the block on a line of its own
+func appendBlock(file *hclwrite.File, block *hclwrite.Block) {
+	file.Body().AppendBlock(block)
+	file.Body().AppendNewline()
+}
+
+func (f *terraformFiles) file(name string) *hclwrite.File {
+	if f.files[name] == nil {
+		f.files[name] = hclwrite.NewEmptyFile()
+	}
+	return f.files[name]
+}
+
+// render returns the formatted content of all files that contain any blocks
+func (f *terraformFiles) render() map[string][]byte {
+	result := make(map[string][]byte, len(f.files))
+	for name, file := range f.files {
+		if len(file.Body().Blocks()) == 0 {
+			continue
+		}
+
+		content := strings.Join(f.header, "\n") + "\n" + string(file.Bytes())
+		formatted := formatFile([]byte(content))
+		if err := validateFile(formatted); err != nil {
+			log.Error().Msgf("The generated terraform code of %s is invalid. "+
+				"This is a bug in mach composer. Please report the issue at "+
+				"https://github.com/mach-composer/mach-composer-cli", name)
+		}
+		result[name] = formatted
+	}
+	return result
+}
+
+func parseBlocks(content string) (*hclwrite.File, error) {
+	file, diags := hclwrite.ParseConfig([]byte(content), "generated.tf", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("generated terraform code is invalid: %w", diags)
+	}
+	return file, nil
+}
+
+// writeFiles writes the files to the given directory, and removes the files of previous generations that are not
+// generated anymore. Only files that start with the generated header are removed, so other files are kept. Files that
+// already have the generated content are not touched. The names of the files that changed are returned, and with
+// dryRun set nothing is written or removed.
+func writeFiles(path string, files map[string][]byte, dryRun bool) ([]string, error) {
+	if !dryRun {
+		if err := os.MkdirAll(path, 0700); err != nil {
+			return nil, fmt.Errorf("error creating directory structure: %w", err)
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var changed []string
+	for _, name := range names {
+		filename := filepath.Join(path, name)
+		written, err := writeFile(filename, files[name], 0700, dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("error writing file: %w", err)
+		}
+		if !written {
+			log.Debug().Msgf("Unchanged %s", filename)
+			continue
+		}
+		if !dryRun {
+			log.Info().Msgf("Writing %s", filename)
+		}
+		changed = append(changed, name)
+	}
+
+	existing, err := filepath.Glob(filepath.Join(path, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	for _, filename := range existing {
+		if _, ok := files[filepath.Base(filename)]; ok {
+			continue
+		}
+
+		generated, err := isGeneratedFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		if !generated {
+			continue
+		}
+
+		changed = append(changed, filepath.Base(filename))
+		if dryRun {
+			continue
+		}
+		log.Info().Msgf("Removing %s", filename)
+		if err := os.Remove(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("error removing file: %w", err)
+		}
+	}
+
+	return changed, nil
+}
+
+// writeFile writes the content to the file, unless the file already has this content, so the modification time is
+// only updated on actual changes. It returns whether the file changed, or would change when dryRun is set.
+func writeFile(filename string, content []byte, perm os.FileMode, dryRun bool) (bool, error) {
+	existing, err := os.ReadFile(filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	if err == nil && bytes.Equal(existing, content) {
+		return false, nil
+	}
+	if dryRun {
+		return true, nil
+	}
+	return true, os.WriteFile(filename, content, perm)
+}
+
+func isGeneratedFile(filename string) (bool, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return false, scanner.Err()
+	}
+	return strings.TrimSpace(scanner.Text()) == generatedHeader, nil
+}
@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerraformFiles(t *testing.T) {
+	files := newTerraformFiles("# site: my-site")
+
+	require.NoError(t, files.addTerraformConfig(`terraform {
+backend "s3" {
+bucket = "state-bucket"
+}
+
+required_providers {
+aws = {
+version = "~> 5.0"
+}
+}
+}`))
+	require.NoError(t, files.add(fileSourcesFile, "\n"))
+	require.NoError(t, files.addResources(`# Configuring AWS
+provider "aws" {
+region = "eu-west-1"
+}
+
+locals {
+tags = {}
+}`))
+	require.NoError(t, files.add("my-component.tf", `# Component: my-component
+module "my-component" {
+source = "./component"
+}`))
+
+	content := files.render()
+	assert.Len(t, content, 4)
+	assert.NotContains(t, content, fileSourcesFile)
+
+	assert.Equal(t, `# This file is auto-generated by MACH composer
+# site: my-site
+terraform {
+  backend "s3" {
+    bucket = "state-bucket"
+  }
+}
+`, string(content[backendFile]))
+
+	assert.Equal(t, `# This file is auto-generated by MACH composer
+# site: my-site
+terraform {
+  required_providers {
+    aws = {
+      version = "~> 5.0"
+    }
+  }
+}
+
+# Configuring AWS
+provider "aws" {
+  region = "eu-west-1"
+}
+`, string(content[providersFile]))
+
+	assert.Contains(t, string(content[resourcesFile]), "locals {")
+	assert.Contains(t, string(content["my-component.tf"]), "module \"my-component\" {")
+}
+
+func TestTerraformFilesInvalid(t *testing.T) {
+	files := newTerraformFiles()
+	assert.ErrorContains(t, files.add(resourcesFile, "locals {"), "generated terraform code is invalid")
+}
+
+func TestWriteFilesRemovesStaleFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(generatedHeader+"\n# site: my-site\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "custom.tf"), []byte("locals {}\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secrets.yaml"), []byte(generatedHeader+"\n"), 0600))
+
+	changed, err := writeFiles(dir, map[string][]byte{
+		backendFile: []byte(generatedHeader + "\nterraform {}\n"),
+	}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{backendFile, "main.tf"}, changed)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{backendFile, "custom.tf", "secrets.yaml"}, names)
+}
+
+func TestWriteFilesSkipsUnchangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		backendFile:   []byte(generatedHeader + "\nterraform {}\n"),
+		resourcesFile: []byte(generatedHeader + "\nlocals {}\n"),
+	}
+	changed, err := writeFiles(dir, files, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{backendFile, resourcesFile}, changed)
+
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, backendFile), past, past))
+
+	changed, err = writeFiles(dir, files, false)
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+	info, err := os.Stat(filepath.Join(dir, backendFile))
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(past))
+
+	// A dry run reports the changes without writing them
+	files[resourcesFile] = []byte(generatedHeader + "\nlocals {\n  a = 1\n}\n")
+	changed, err = writeFiles(dir, files, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{resourcesFile}, changed)
+	content, err := os.ReadFile(filepath.Join(dir, resourcesFile))
+	require.NoError(t, err)
+	assert.Equal(t, generatedHeader+"\nlocals {}\n", string(content))
+
+	changed, err = writeFiles(filepath.Join(dir, "missing"), files, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{backendFile, resourcesFile}, changed)
+	assert.NoDirExists(t, filepath.Join(dir, "missing"))
+}
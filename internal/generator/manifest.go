@@ -0,0 +1,146 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const manifestFilename = ".mach-composer-manifest.json"
+
+// manifest records the directories that are generated in the directory of a project, so directories of sites and
+// components that are removed from the config can be found
+type manifest struct {
+	Version     int      `json:"version"`
+	Directories []string `json:"directories"`
+}
+
+func loadManifest(dir string) (*manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFilename))
+	if errors.Is(err, os.ErrNotExist) {
+		return &manifest{Version: 1}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m := &manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", manifestFilename, err)
+	}
+	return m, nil
+}
+
+func (m *manifest) save(dir string) error {
+	sort.Strings(m.Directories)
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("error creating directory structure: %w", err)
+	}
+	_, err = writeFile(filepath.Join(dir, manifestFilename), append(data, '\n'), 0600, false)
+	return err
+}
+
+// updateManifest records the given generated directories in the manifest of the project directory. Directories of a
+// previous generation that are not generated anymore are removed when prune is set, and reported otherwise.
+// Directories that still hold local terraform state are never removed, as that would lose track of the resources in it.
+func updateManifest(root string, dirs []string, prune bool) error {
+	previous, err := loadManifest(root)
+	if err != nil {
+		return err
+	}
+
+	current := &manifest{Version: 1}
+	generated := map[string]bool{}
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		generated[rel] = true
+		current.Directories = append(current.Directories, rel)
+	}
+
+	for _, rel := range previous.Directories {
+		if generated[rel] || rel == "." || strings.HasPrefix(rel, "../") || filepath.IsAbs(rel) {
+			continue
+		}
+
+		dir := filepath.Join(root, filepath.FromSlash(rel))
+		if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if !prune {
+			log.Warn().Msgf("Directory %s is not generated anymore. Run `mach-composer generate --prune` to remove it", dir)
+			current.Directories = append(current.Directories, rel)
+			continue
+		}
+
+		hasState, err := hasLocalState(dir)
+		if err != nil {
+			return err
+		}
+		if hasState {
+			log.Warn().Msgf("Not removing %s, as it still holds local terraform state. Destroy the resources or move "+
+				"the state first", dir)
+			current.Directories = append(current.Directories, rel)
+			continue
+		}
+
+		log.Info().Msgf("Removing %s, as it is not generated anymore", dir)
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", dir, err)
+		}
+	}
+
+	return current.save(root)
+}
+
+// hasLocalState returns true if the directory contains a local terraform state file with any resources. State files
+// that cannot be parsed are considered to hold resources.
+func hasLocalState(dir string) (bool, error) {
+	found := false
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// The state in .terraform only holds the backend configuration
+		if d.IsDir() && d.Name() == ".terraform" {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".tfstate") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if len(strings.TrimSpace(string(data))) == 0 {
+			return nil
+		}
+
+		var state struct {
+			Resources []json.RawMessage `json:"resources"`
+		}
+		if err := json.Unmarshal(data, &state); err != nil || len(state.Resources) > 0 {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found, err
+}
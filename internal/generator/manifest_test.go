@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateManifest(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"site-1", "site-1/component-1", "site-2", "site-3"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0700))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(root, "site-3", "terraform.tfstate"),
+		[]byte(`{"version": 4, "resources": [{"type": "null_resource"}]}`), 0600))
+
+	dirs := func(names ...string) []string {
+		var result []string
+		for _, name := range names {
+			result = append(result, filepath.Join(root, name))
+		}
+		return result
+	}
+
+	require.NoError(t, updateManifest(root, dirs("site-1", "site-1/component-1", "site-2", "site-3"), false))
+	m, err := loadManifest(root)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"site-1", "site-1/component-1", "site-2", "site-3"}, m.Directories)
+
+	// Orphaned directories are only reported without prune
+	require.NoError(t, updateManifest(root, dirs("site-1"), false))
+	assert.DirExists(t, filepath.Join(root, "site-2"))
+	m, err = loadManifest(root)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"site-1", "site-1/component-1", "site-2", "site-3"}, m.Directories)
+
+	// Directories with local state are kept when pruning
+	require.NoError(t, updateManifest(root, dirs("site-1"), true))
+	assert.DirExists(t, filepath.Join(root, "site-1"))
+	assert.NoDirExists(t, filepath.Join(root, "site-1", "component-1"))
+	assert.NoDirExists(t, filepath.Join(root, "site-2"))
+	assert.DirExists(t, filepath.Join(root, "site-3"))
+	m, err = loadManifest(root)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"site-1", "site-3"}, m.Directories)
+}
+
+func TestHasLocalState(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".terraform"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".terraform", "terraform.tfstate"), []byte(`{"backend": {}}`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "terraform.tfstate"), []byte(`{"version": 4, "resources": []}`), 0600))
+
+	found, err := hasLocalState(dir)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "terraform.tfstate.backup"), []byte("{}"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "terraform.tfstate"), []byte("not json"), 0600))
+	found, err = hasLocalState(dir)
+	require.NoError(t, err)
+	assert.True(t, found)
+}
@@ -0,0 +1,46 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+)
+
+// renderRefactoringBlocks uses templates/refactoring.tmpl to generate the terraform import and moved blocks. When a
+// module name is given all addresses are prefixed with the module address, so they can be configured relative to the
+// component
+func renderRefactoringBlocks(
+	ctx context.Context, cfg *config.MachConfig, module string, imports []config.ImportConfig, moved []config.MovedConfig) (string, error) {
+	if len(imports) == 0 && len(moved) == 0 {
+		return "", nil
+	}
+
+	tc := refactoringContext{
+		Imports: make([]config.ImportConfig, len(imports)),
+		Moved:   make([]config.MovedConfig, len(moved)),
+	}
+
+	for i, item := range imports {
+		tc.Imports[i] = config.ImportConfig{
+			To:       moduleAddress(module, item.To),
+			ID:       hclStringEscaper.Replace(item.ID),
+			Provider: item.Provider,
+		}
+	}
+
+	for i, item := range moved {
+		tc.Moved[i] = config.MovedConfig{
+			From: moduleAddress(module, item.From),
+			To:   moduleAddress(module, item.To),
+		}
+	}
+
+	return renderTemplate(ctx, cfg, "refactoring.tmpl", tc)
+}
+
+func moduleAddress(module, address string) string {
+	if module == "" {
+		return address
+	}
+	return fmt.Sprintf("module.%s.%s", module, address)
+}
@@ -0,0 +1,59 @@
+package generator
+
+import (
+	"context"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRenderRefactoringBlocksEmpty(t *testing.T) {
+	val, err := renderRefactoringBlocks(context.Background(), nil, "my-component", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "", val)
+}
+
+func TestRenderRefactoringBlocksModulePrefix(t *testing.T) {
+	val, err := renderRefactoringBlocks(context.Background(), nil, "my-component",
+		[]config.ImportConfig{{To: "aws_s3_bucket.this", ID: "my-bucket", Provider: "aws.us_east_1"}},
+		[]config.MovedConfig{{From: "aws_s3_bucket.old", To: "aws_s3_bucket.new"}},
+	)
+	require.NoError(t, err)
+
+	formatted := string(formatFile([]byte(val)))
+	assert.NoError(t, validateFile([]byte(formatted)))
+	assert.Equal(t, `# Refactoring
+import {
+  to       = module.my-component.aws_s3_bucket.this
+  id       = "my-bucket"
+  provider = aws.us_east_1
+}
+
+moved {
+  from = module.my-component.aws_s3_bucket.old
+  to   = module.my-component.aws_s3_bucket.new
+}
+`, formatted)
+}
+
+func TestRenderRefactoringBlocksSite(t *testing.T) {
+	val, err := renderRefactoringBlocks(context.Background(), nil, "",
+		[]config.ImportConfig{{To: "aws_s3_bucket.this", ID: "my-bucket"}},
+		nil,
+	)
+	require.NoError(t, err)
+	assert.Contains(t, val, "to = aws_s3_bucket.this")
+	assert.NotContains(t, val, "provider")
+}
+
+func TestRenderRefactoringBlocksEscapesID(t *testing.T) {
+	val, err := renderRefactoringBlocks(context.Background(), nil, "",
+		[]config.ImportConfig{{To: "aws_s3_bucket.this", ID: `my-"bucket"\${var.x}`}}, nil,
+	)
+	require.NoError(t, err)
+
+	formatted := string(formatFile([]byte(val)))
+	assert.NoError(t, validateFile([]byte(formatted)))
+	assert.Contains(t, formatted, `id = "my-\"bucket\"\\$${var.x}"`)
+}
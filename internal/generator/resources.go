@@ -1,20 +1,12 @@
 package generator
 
 import (
-	"embed"
+	"context"
+
 	"github.com/mach-composer/mach-composer-cli/internal/config"
-	"github.com/mach-composer/mach-composer-cli/internal/utils"
 )
 
-//go:embed templates/file_sources.tmpl
-var fileSourcesTpl embed.FS
-
 // renderFileSources uses templates/file_sources.tmpl to generate a terraform snippet for each file source
-func renderFileSources(cfg *config.MachConfig, siteConfig config.SiteConfig) (string, error) {
-	tpl, err := fileSourcesTpl.ReadFile("templates/file_sources.tmpl")
-	if err != nil {
-		return "", err
-	}
-
-	return utils.RenderGoTemplate(string(tpl), cfg.Variables.GetEncryptedSources(siteConfig.Identifier))
+func renderFileSources(ctx context.Context, cfg *config.MachConfig, siteConfig config.SiteConfig) (string, error) {
+	return renderTemplate(ctx, cfg, "file_sources.tmpl", cfg.Variables.GetEncryptedSources(siteConfig.Identifier))
 }
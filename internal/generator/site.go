@@ -5,41 +5,51 @@ import (
 	"fmt"
 	"github.com/mach-composer/mach-composer-cli/internal/config"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
-	"github.com/mach-composer/mach-composer-cli/internal/utils"
 	"sort"
-	"strings"
 )
 
-// renderSite is responsible for generating the `site.tf` file. Therefore, it is
-// the main entrypoint for generating the terraform file for each site.
-func renderSite(ctx context.Context, cfg *config.MachConfig, n *graph.Site) (string, error) {
+// renderSite is responsible for generating the terraform files of a site. Therefore, it is the main entrypoint for
+// generating the terraform files for each site.
+func renderSite(ctx context.Context, cfg *config.MachConfig, n *graph.Site) (*terraformFiles, error) {
 	nestedNodes := n.NestedNodes
 
-	result := []string{
-		"# This file is auto-generated by MACH composer",
-		fmt.Sprintf("# site: %s", n.Identifier()),
-	}
+	result := newTerraformFiles(fmt.Sprintf("# site: %s", n.Identifier()))
 
 	// Render the terraform config
-	val, err := renderSiteTerraformConfig(cfg, n)
+	val, err := renderSiteTerraformConfig(ctx, cfg, n)
 	if err != nil {
-		return "", fmt.Errorf("failed to render terraform config: %w", err)
+		return nil, fmt.Errorf("failed to render terraform config: %w", err)
+	}
+	if err := result.addTerraformConfig(val); err != nil {
+		return nil, fmt.Errorf("failed to render terraform config: %w", err)
 	}
-	result = append(result, val)
 
 	// Render all the file sources
-	val, err = renderFileSources(cfg, n.SiteConfig)
+	val, err = renderFileSources(ctx, cfg, n.SiteConfig)
 	if err != nil {
-		return "", fmt.Errorf("failed to render file sources: %w", err)
+		return nil, fmt.Errorf("failed to render file sources: %w", err)
+	}
+	if err := result.add(fileSourcesFile, val); err != nil {
+		return nil, fmt.Errorf("failed to render file sources: %w", err)
 	}
-	result = append(result, val)
 
 	// Render all the global resources
-	val, err = renderSiteResources(cfg, n)
+	val, err = renderSiteResources(ctx, cfg, n)
 	if err != nil {
-		return "", fmt.Errorf("failed to render resources: %w", err)
+		return nil, fmt.Errorf("failed to render resources: %w", err)
+	}
+	if err := result.addResources(val); err != nil {
+		return nil, fmt.Errorf("failed to render resources: %w", err)
+	}
+
+	// Render the import and moved blocks configured on the site itself
+	val, err = renderRefactoringBlocks(ctx, cfg, "", n.SiteConfig.Imports, n.SiteConfig.Moved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render import and moved blocks: %w", err)
+	}
+	if err := result.add(refactoringFile, val); err != nil {
+		return nil, fmt.Errorf("failed to render import and moved blocks: %w", err)
 	}
-	result = append(result, val)
 
 	sort.Slice(nestedNodes, func(i, j int) bool {
 		return nestedNodes[i].Identifier() < nestedNodes[j].Identifier()
@@ -51,20 +61,21 @@ func renderSite(ctx context.Context, cfg *config.MachConfig, n *graph.Site) (str
 		}
 		val, err = renderComponentModule(ctx, cfg, component)
 		if err != nil {
-			return "", fmt.Errorf("failed to render site component: %w", err)
+			return nil, fmt.Errorf("failed to render site component: %w", err)
+		}
+		if err := result.add(componentFile(component), val); err != nil {
+			return nil, fmt.Errorf("failed to render site component: %w", err)
 		}
-		result = append(result, val)
 	}
 
-	return strings.Join(result, "\n"), nil
-}
-
-func renderSiteTerraformConfig(cfg *config.MachConfig, n *graph.Site) (string, error) {
-	tpl, err := templates.ReadFile("templates/terraform.tmpl")
-	if err != nil {
-		return "", err
+	if err := result.checkProviders(); err != nil {
+		return nil, err
 	}
 
+	return result, nil
+}
+
+func renderSiteTerraformConfig(ctx context.Context, cfg *config.MachConfig, n *graph.Site) (string, error) {
 	var providers []string
 	for _, plugin := range cfg.Plugins.All() {
 		content, err := plugin.RenderTerraformProviders(n.SiteConfig.Identifier)
@@ -86,24 +97,26 @@ func renderSiteTerraformConfig(cfg *config.MachConfig, n *graph.Site) (string, e
 		return "", err
 	}
 
-	templateContext := struct {
-		Providers     []string
-		BackendConfig string
-		IncludeSOPS   bool
-	}{
+	tc := terraformContext{
 		Providers:     providers,
 		BackendConfig: b,
 		IncludeSOPS:   cfg.Variables.HasEncrypted(n.SiteConfig.Identifier),
 	}
-	return utils.RenderGoTemplate(string(tpl), templateContext)
-}
 
-func renderSiteResources(cfg *config.MachConfig, n *graph.Site) (string, error) {
-	tpl, err := templates.ReadFile("templates/resources.tmpl")
-	if err != nil {
+	var components []*config.ComponentConfig
+	for _, component := range n.NestedNodes {
+		if component.SiteComponentConfig.Deployment.Type == config.DeploymentSite {
+			components = append(components, component.SiteComponentConfig.Definition)
+		}
+	}
+	if err := mergeComponentTerraform(&tc, components); err != nil {
 		return "", err
 	}
 
+	return renderTemplate(ctx, cfg, "terraform.tmpl", tc)
+}
+
+func renderSiteResources(ctx context.Context, cfg *config.MachConfig, n *graph.Site) (string, error) {
 	var resources []string
 	for _, plugin := range cfg.Plugins.All() {
 		content, err := plugin.RenderTerraformResources(n.SiteConfig.Identifier)
@@ -116,5 +129,5 @@ func renderSiteResources(cfg *config.MachConfig, n *graph.Site) (string, error)
 		}
 	}
 
-	return utils.RenderGoTemplate(string(tpl), resources)
+	return renderTemplate(ctx, cfg, "resources.tmpl", resources)
 }
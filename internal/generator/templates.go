@@ -0,0 +1,188 @@
+package generator
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+)
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+// The context types below are passed to the templates, and are a stable contract for templates that are overridden
+// through `mach_composer.templates_dir`. Fields are only added to them, never renamed or removed. The context of
+// resources.tmpl is the list of resources rendered by the plugins, and the context of file_sources.tmpl the list of
+// variable files (config.FileSource) of the site.
+
+// terraformContext is the context of terraform.tmpl, which renders the terraform block of a site or site component
+type terraformContext struct {
+	// Providers are the required_providers entries rendered by the plugins
+	Providers []string
+	// BackendConfig is the backend block of the remote state
+	BackendConfig string
+	// IncludeSOPS is set when the site uses encrypted variables, which are read with the sops provider
+	IncludeSOPS bool
+	// RequiredVersion is the terraform version constraint of the components, if any, escaped for a terraform string
+	RequiredVersion string
+	// ProviderConfigs are the provider blocks configured in the terraform settings of the components
+	ProviderConfigs []string
+}
+
+// componentContext is the context of site_component.tmpl, which renders the module block and output of a site
+// component
+type componentContext struct {
+	// ComponentName is the name of the component, used as module name
+	ComponentName string
+	// ComponentVersion is the version of the component
+	ComponentVersion string
+	// ComponentHash is not set, and only kept for compatibility
+	ComponentHash string
+	// ComponentVariables is the rendered `variables = {...}` attribute, if any
+	ComponentVariables string
+	// ComponentSecrets is the rendered `secrets = {...}` attribute, if any
+	ComponentSecrets string
+	// SiteName is the identifier of the site
+	SiteName string
+	// Environment is the global environment
+	Environment string
+	// Source is the module source of the component
+	Source string
+	// ModuleVersion is the version constraint of a registry module, rendered as the `version` argument. It is escaped for
+	// a terraform string.
+	ModuleVersion string
+	// PluginResources are the resources the plugins render next to the module
+	PluginResources []string
+	// PluginProviders are the provider mappings the plugins pass to the module
+	PluginProviders []string
+	// PluginDependsOn are the depends_on entries the plugins add to the module
+	PluginDependsOn []string
+	// PluginVariables are the module attributes rendered by the plugins
+	PluginVariables []string
+	// HasCloudIntegration is set when the component is integrated with the global cloud
+	HasCloudIntegration bool
+	// Outputs are the declared outputs of the component. When empty all module outputs are rendered as a single
+	// sensitive output named after the component
+	Outputs []componentOutput
+}
+
+// componentOutput is a declared output of a component, which is rendered as a separate terraform output
+type componentOutput struct {
+	// Name is the name of the terraform output
+	Name string
+	// ModuleOutput is the name of the output of the component module
+	ModuleOutput string
+	Description  string
+	Sensitive    bool
+}
+
+// refactoringContext is the context of refactoring.tmpl, which renders import and moved blocks. The addresses are
+// already prefixed with the module address of the component.
+type refactoringContext struct {
+	Imports []config.ImportConfig
+	Moved   []config.MovedConfig
+}
+
+// renderTemplate renders the template with the given name, using the template of the templates directory if it
+// exists and the embedded template otherwise
+func renderTemplate(ctx context.Context, cfg *config.MachConfig, name string, data any) (string, error) {
+	tpl, err := readTemplate(cfg, name)
+	if err != nil {
+		return "", err
+	}
+
+	if w := templateContextFromContext(ctx); w != nil {
+		if err := w.write(name, data); err != nil {
+			return "", err
+		}
+	}
+
+	return utils.RenderGoTemplate(string(tpl), data)
+}
+
+func readTemplate(cfg *config.MachConfig, name string) ([]byte, error) {
+	if cfg != nil && cfg.MachComposer.TemplatesDir != "" {
+		filename := filepath.Join(cfg.MachComposer.TemplatesDir, name)
+		data, err := os.ReadFile(filename)
+		if err == nil {
+			log.Debug().Msgf("Using template %s", filename)
+			return data, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read template %s: %w", filename, err)
+		}
+	}
+	return templates.ReadFile("templates/" + name)
+}
+
+// checkTemplatesDir warns about templates in the templates directory that do not override any template, as these are
+// most likely misspelled
+func checkTemplatesDir(cfg *config.MachConfig) error {
+	dir := cfg.MachComposer.TemplatesDir
+	if dir == "" {
+		return nil
+	}
+
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("templates directory %s does not exist", dir)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return err
+	}
+
+	for _, filename := range files {
+		if _, err := fs.Stat(templates, "templates/"+filepath.Base(filename)); err != nil {
+			log.Warn().Msgf("Template %s does not override any template and is ignored", filename)
+		}
+	}
+	return nil
+}
+
+const templateContextKey = "template-context"
+
+// templateContextWriter writes the context of every rendered template of a node as json
+type templateContextWriter struct {
+	w    io.Writer
+	node string
+}
+
+func (t *templateContextWriter) write(name string, data any) error {
+	out, err := json.MarshalIndent(struct {
+		Node     string `json:"node"`
+		Template string `json:"template"`
+		Context  any    `json:"context"`
+	}{
+		Node:     t.node,
+		Template: name,
+		Context:  data,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize the context of template %s: %w", name, err)
+	}
+
+	_, err = fmt.Fprintln(t.w, string(out))
+	return err
+}
+
+func contextWithTemplateContext(ctx context.Context, w *templateContextWriter) context.Context {
+	return context.WithValue(ctx, templateContextKey, w)
+}
+
+func templateContextFromContext(ctx context.Context) *templateContextWriter {
+	if v := ctx.Value(templateContextKey); v != nil {
+		return v.(*templateContextWriter)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+)
+
+func TestRenderTemplateEmbedded(t *testing.T) {
+	val, err := renderTemplate(context.Background(), &config.MachConfig{}, "resources.tmpl", []string{"resource \"a\" \"b\" {}"})
+	require.NoError(t, err)
+	assert.Contains(t, val, "resource \"a\" \"b\" {}")
+}
+
+func TestRenderTemplateOverride(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "resources.tmpl"), []byte("# Custom {{ len . }}"), 0600))
+
+	cfg := &config.MachConfig{MachComposer: config.MachComposer{TemplatesDir: dir}}
+	require.NoError(t, checkTemplatesDir(cfg))
+
+	val, err := renderTemplate(context.Background(), cfg, "resources.tmpl", []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, "# Custom 2", val)
+
+	// Templates that are not overridden use the embedded template
+	val, err = renderTemplate(context.Background(), cfg, "file_sources.tmpl", []config.FileSource{{Filename: "vars.yaml"}})
+	require.NoError(t, err)
+	assert.Contains(t, val, `filename = "vars.yaml"`)
+
+	cfg.MachComposer.TemplatesDir = filepath.Join(dir, "missing")
+	assert.ErrorContains(t, checkTemplatesDir(cfg), "does not exist")
+}
+
+func TestRenderTemplateContext(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ctx := contextWithTemplateContext(context.Background(), &templateContextWriter{w: buf, node: "my-site"})
+
+	_, err := renderTemplate(ctx, nil, "terraform.tmpl", terraformContext{
+		Providers:     []string{"aws = {}"},
+		BackendConfig: "backend \"local\" {}",
+	})
+	require.NoError(t, err)
+
+	var result map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &result))
+	assert.Equal(t, map[string]any{
+		"node":     "my-site",
+		"template": "terraform.tmpl",
+		"context": map[string]any{
+			"Providers":       []any{"aws = {}"},
+			"BackendConfig":   "backend \"local\" {}",
+			"IncludeSOPS":     false,
+			"RequiredVersion": "",
+			"ProviderConfigs": nil,
+		},
+	}, result)
+}
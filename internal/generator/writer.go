@@ -2,11 +2,11 @@ package generator
 
 import (
 	"context"
-	"embed"
 	"errors"
 	"fmt"
 	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
 	"github.com/mach-composer/mach-composer-cli/internal/state"
 	"github.com/rs/zerolog/log"
@@ -14,19 +14,47 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/mach-composer/mach-composer-cli/internal/config"
 )
 
-type GenerateOptions struct{}
-
-//go:embed templates/*.tmpl
-var templates embed.FS
+type GenerateOptions struct {
+	// TemplateContext receives the context of every rendered template as json. When set no files are written
+	TemplateContext io.Writer
+	// Prune removes the directories of sites and components that are not generated anymore
+	Prune bool
+	// Check only compares the generated files with the files on disk. Nothing is written, and an error is returned
+	// when the files of any node would change
+	Check bool
+}
 
 // Write is the main entrypoint for this module. It takes the given MachConfig and graph and iterates the nodes to generate
 // the required terraform files.
-func Write(ctx context.Context, cfg *config.MachConfig, g *graph.Graph, _ *GenerateOptions) error {
-	for _, n := range g.Vertices() {
+func Write(ctx context.Context, cfg *config.MachConfig, g *graph.Graph, opts *GenerateOptions) error {
+	if opts == nil {
+		opts = &GenerateOptions{}
+	}
+
+	if err := checkTemplatesDir(cfg); err != nil {
+		return err
+	}
+
+	if err := validateOutputReferences(cfg); err != nil {
+		return err
+	}
+
+	if err := validateOutputNames(cfg); err != nil {
+		return err
+	}
+
+	vertices := g.Vertices()
+	sort.Slice(vertices, func(i, j int) bool {
+		return vertices[i].Identifier() < vertices[j].Identifier()
+	})
+
+	for _, n := range vertices {
 		sr, err := state.NewRenderer(
 			state.Type(cfg.Global.TerraformStateProvider),
 			n.Identifier(),
@@ -47,71 +75,98 @@ func Write(ctx context.Context, cfg *config.MachConfig, g *graph.Graph, _ *Gener
 		}
 	}
 
-	for _, n := range g.Vertices() {
-		switch n.(type) {
-		case *graph.Project:
-			log.Debug().Msgf("No global files to generate for project %s", n.Path())
-			break
-		case *graph.Site:
-			if err := copySecrets(cfg, n.Identifier(), n.Path()); err != nil {
-				return err
-			}
-			body, err := renderSite(ctx, cfg, n.(*graph.Site))
-			if err != nil {
+	var outdated []error
+	for _, n := range vertices {
+		if opts.TemplateContext != nil {
+			if err := printTemplateContext(ctx, cfg, n, opts.TemplateContext); err != nil {
 				return err
 			}
+			continue
+		}
 
-			if err = writeContent(n.Path(), body); err != nil {
-				return err
-			}
-			break
+		var files *terraformFiles
+		var err error
+		switch n := n.(type) {
+		case *graph.Project:
+			log.Debug().Msgf("No global files to generate for project %s", n.Path())
+			continue
+		case *graph.Site:
+			files, err = renderSite(ctx, cfg, n)
 		case *graph.SiteComponent:
-			if err := copySecrets(cfg, n.Identifier(), n.Path()); err != nil {
-				return err
-			}
-
-			body, err := renderSiteComponent(ctx, cfg, n.(*graph.SiteComponent))
-			if err != nil {
-				return err
-			}
-
-			if err = writeContent(n.Path(), body); err != nil {
-				return err
-			}
-			break
+			files, err = renderSiteComponent(ctx, cfg, n)
 		default:
 			return fmt.Errorf("unknown node type %T", n)
 		}
+		if err != nil {
+			return err
+		}
 
+		changed, err := copySecrets(cfg, n.Identifier(), n.Path(), opts.Check)
+		if err != nil {
+			return err
+		}
+		written, err := writeNodeFiles(cfg, n, files, opts.Check)
+		if err != nil {
+			return err
+		}
+		changed = append(changed, written...)
+
+		switch {
+		case len(changed) == 0:
+			log.Info().Msgf("Generated files of %s are up to date", n.Identifier())
+		case opts.Check:
+			log.Warn().Msgf("Generated files of %s would change: %s", n.Identifier(), strings.Join(changed, ", "))
+			outdated = append(outdated, fmt.Errorf("%s: %s", n.Identifier(), strings.Join(changed, ", ")))
+		default:
+			log.Info().Msgf("Generated files of %s changed: %s", n.Identifier(), strings.Join(changed, ", "))
+		}
 	}
 
-	return nil
-}
-
-func writeContent(path, content string) error {
-	filename := filepath.Join(path, "main.tf")
-
-	log.Info().Msgf("Writing %s", filename)
+	if len(outdated) > 0 {
+		return cli.NewGroupedError("generated files are not up to date, run `mach-composer generate`", outdated)
+	}
 
-	// Format and validate the file
-	formatted := formatFile([]byte(content))
-	if err := validateFile(formatted); err != nil {
-		log.Error().Msg("The generated terraform code is invalid. " +
-			"This is a bug in mach composer. Please report the issue at " +
-			"https://github.com/mach-composer/mach-composer-cli")
+	if opts.TemplateContext != nil || opts.Check || g.StartNode == nil {
+		return nil
 	}
 
-	if err := os.MkdirAll(path, 0700); err != nil {
-		return fmt.Errorf("error creating directory structure: %w", err)
+	var dirs []string
+	for _, n := range vertices {
+		if n.Type() != graph.ProjectType {
+			dirs = append(dirs, n.Path())
+		}
 	}
+	return updateManifest(g.StartNode.Path(), dirs, opts.Prune)
+}
+
+// printTemplateContext renders the node and writes the context of the templates to w instead of writing the files
+func printTemplateContext(ctx context.Context, cfg *config.MachConfig, n graph.Node, w io.Writer) error {
+	ctx = contextWithTemplateContext(ctx, &templateContextWriter{w: w, node: n.Identifier()})
 
-	if err := os.WriteFile(filename, formatted, 0700); err != nil {
-		return fmt.Errorf("error writing file: %w", err)
+	var err error
+	switch n := n.(type) {
+	case *graph.Site:
+		_, err = renderSite(ctx, cfg, n)
+	case *graph.SiteComponent:
+		_, err = renderSiteComponent(ctx, cfg, n)
 	}
+	return err
+}
 
-	log.Info().Msgf("Wrote files for path %s", path)
+// writeNodeFiles writes the generated files and the extra terraform code of the node, and returns the names of the
+// files that changed
+func writeNodeFiles(cfg *config.MachConfig, n graph.Node, files *terraformFiles, dryRun bool) ([]string, error) {
+	content := files.render()
 
-	return nil
+	extra, err := renderExtraTerraform(extraTerraform(cfg, n))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.Identifier(), err)
+	}
+	if extra != "" {
+		content[extraTerraformFilename] = []byte(extra)
+	}
+
+	return writeFiles(n.Path(), content, dryRun)
 }
 
 func formatFile(src []byte) []byte {
@@ -155,50 +210,36 @@ func validateFile(src []byte) error {
 	return nil
 }
 
-func copyFile(srcPath, dstPath string) error {
-	// Open the source file
-	src, err := os.Open(srcPath)
-	if err != nil {
-		return err
-	}
-	defer src.Close()
-
-	// Read the contents of the source file
-	srcContents, err := io.ReadAll(src)
-	if err != nil {
-		return err
-	}
-
-	// Create the destination file
-	dst, err := os.Create(dstPath)
-	if err != nil {
-		return err
-	}
-	defer dst.Close()
-
-	// WriteLock the contents of the source file to the destination file
-	_, err = dst.Write(srcContents)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func copySecrets(cfg *config.MachConfig, identifier, outPath string) error {
+// copySecrets copies the encrypted variable files of the node to its directory, and returns the names of the files
+// that changed
+func copySecrets(cfg *config.MachConfig, identifier, outPath string, dryRun bool) ([]string, error) {
+	var changed []string
 	for _, fs := range cfg.Variables.GetEncryptedSources(identifier) {
 		target := filepath.Join(outPath, fs.Filename)
-		log.Info().Msgf("Copying %s", target)
 
-		// This can refer to a file outside the current directory, so we need to create the directory structure
-		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
-			return fmt.Errorf("error creating directory structure for variables: %w", err)
+		content, err := os.ReadFile(fs.Filename)
+		if err != nil {
+			return nil, fmt.Errorf("error reading extra file: %w", err)
+		}
+
+		if !dryRun {
+			// This can refer to a file outside the current directory, so we need to create the directory structure
+			if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+				return nil, fmt.Errorf("error creating directory structure for variables: %w", err)
+			}
 		}
 
-		if err := copyFile(fs.Filename, target); err != nil {
-			return fmt.Errorf("error writing extra file: %w", err)
+		written, err := writeFile(target, content, 0666, dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("error writing extra file: %w", err)
+		}
+		if written {
+			if !dryRun {
+				log.Info().Msgf("Copying %s", target)
+			}
+			changed = append(changed, fs.Filename)
 		}
 	}
 
-	return nil
+	return changed, nil
 }
@@ -1,6 +1,8 @@
 package graph
 
 import (
+	"fmt"
+
 	"github.com/mach-composer/mach-composer-cli/internal/config"
 	"github.com/mach-composer/mach-composer-cli/internal/config/variable"
 	"github.com/mach-composer/mach-composer-cli/internal/utils"
@@ -30,36 +32,78 @@ func HashSiteComponent(sc *SiteComponent) (string, error) {
 		}
 	}
 
+	// The extra terraform files are read, as they can be changed without changing the config itself
+	extraHash, err := hashExtraTerraform(
+		sc.ProjectConfig.Global.ExtraTerraform,
+		sc.SiteConfig.ExtraTerraform,
+		sc.SiteComponentConfig.ExtraTerraform,
+	)
+	if err != nil {
+		return "", err
+	}
+
 	return utils.ComputeHash(struct {
 		Name       string `json:"name"`
 		Definition struct {
-			Name    string        `json:"name"`
-			Version string        `json:"version"`
-			Source  config.Source `json:"source"`
-			Branch  string        `json:"branch"`
+			Name      string                     `json:"name"`
+			Version   string                     `json:"version"`
+			Source    config.Source              `json:"source"`
+			Branch    string                     `json:"branch"`
+			Outputs   []config.ComponentOutput   `json:"outputs,omitempty"`
+			Terraform *config.ComponentTerraform `json:"terraform,omitempty"`
 		} `json:"definition"`
 		Variables     variable.VariablesMap `json:"variables"`
 		Secrets       variable.VariablesMap `json:"secrets"`
 		DependsOn     []string              `json:"depends_on"`
 		Terraform     string                `json:"terraform"`
 		VariablesFile string                `json:"variables_file"`
+		Imports       []config.ImportConfig `json:"imports,omitempty"`
+		Moved         []config.MovedConfig  `json:"moved,omitempty"`
+		Extra         string                `json:"extra_terraform,omitempty"`
 	}{
 		Name: sc.SiteComponentConfig.Name,
 		Definition: struct {
-			Name    string        `json:"name"`
-			Version string        `json:"version"`
-			Source  config.Source `json:"source"`
-			Branch  string        `json:"branch"`
+			Name      string                     `json:"name"`
+			Version   string                     `json:"version"`
+			Source    config.Source              `json:"source"`
+			Branch    string                     `json:"branch"`
+			Outputs   []config.ComponentOutput   `json:"outputs,omitempty"`
+			Terraform *config.ComponentTerraform `json:"terraform,omitempty"`
 		}{
-			Name:    sc.SiteComponentConfig.Definition.Name,
-			Version: sc.SiteComponentConfig.Definition.Version,
-			Source:  sc.SiteComponentConfig.Definition.Source,
-			Branch:  sc.SiteComponentConfig.Definition.Branch,
+			Name:      sc.SiteComponentConfig.Definition.Name,
+			Version:   sc.SiteComponentConfig.Definition.Version,
+			Source:    sc.SiteComponentConfig.Definition.Source,
+			Branch:    sc.SiteComponentConfig.Definition.Branch,
+			Outputs:   sc.SiteComponentConfig.Definition.Outputs,
+			Terraform: sc.SiteComponentConfig.Definition.Terraform,
 		},
 		Variables:     sc.SiteComponentConfig.Variables,
 		Secrets:       sc.SiteComponentConfig.Secrets,
 		DependsOn:     sc.SiteComponentConfig.DependsOn,
 		Terraform:     tfHash,
 		VariablesFile: variablesHash,
+		Imports:       sc.SiteComponentConfig.Imports,
+		Moved:         sc.SiteComponentConfig.Moved,
+		Extra:         extraHash,
 	})
 }
+
+// hashExtraTerraform returns the hash of the content of the given extra terraform code, or an empty string if there is
+// none
+func hashExtraTerraform(extras ...[]config.ExtraTerraform) (string, error) {
+	var contents []string
+	for _, list := range extras {
+		for _, e := range list {
+			content, err := e.Content()
+			if err != nil {
+				return "", fmt.Errorf("failed to read extra terraform %s: %w", e.Name(), err)
+			}
+			contents = append(contents, string(content))
+		}
+	}
+
+	if len(contents) == 0 {
+		return "", nil
+	}
+	return utils.ComputeHash(contents)
+}
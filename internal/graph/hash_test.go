@@ -4,6 +4,9 @@ import (
 	"github.com/mach-composer/mach-composer-cli/internal/config"
 	"github.com/mach-composer/mach-composer-cli/internal/config/variable"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -137,3 +140,32 @@ func TestHashSiteComponentConfigVariablesFileChanged(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotEqual(t, h1, h2)
 }
+
+func TestHashSiteComponentConfigExtraTerraform(t *testing.T) {
+	extra := filepath.Join(t.TempDir(), "locals.tf")
+	require.NoError(t, os.WriteFile(extra, []byte("locals {\n  a = 1\n}\n"), 0600))
+
+	n := &SiteComponent{
+		SiteComponentConfig: config.SiteComponentConfig{
+			Name: "site-component-1",
+			Definition: &config.ComponentConfig{
+				Name:   "site-component-1",
+				Source: "testdata/dirhash",
+			},
+		},
+	}
+
+	h1, err := HashSiteComponent(n)
+	require.NoError(t, err)
+
+	n.SiteComponentConfig.ExtraTerraform = []config.ExtraTerraform{config.ExtraTerraform(extra)}
+	h2, err := HashSiteComponent(n)
+	require.NoError(t, err)
+	assert.NotEqual(t, h1, h2)
+
+	// Changing the file changes the hash
+	require.NoError(t, os.WriteFile(extra, []byte("locals {\n  a = 2\n}\n"), 0600))
+	h3, err := HashSiteComponent(n)
+	require.NoError(t, err)
+	assert.NotEqual(t, h2, h3)
+}
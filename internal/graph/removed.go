@@ -0,0 +1,32 @@
+package graph
+
+import "github.com/mach-composer/mach-composer-cli/internal/config"
+
+// Removed is a node that was applied before, but is not part of the configuration anymore. It is not part of a
+// graph, and only used to destroy the resources that were created for it.
+type Removed struct {
+	baseNode
+	// ProjectConfig is the current configuration, of which the policies apply to the removed node
+	ProjectConfig config.MachConfig
+	// Site is the identifier of the site the node belonged to, and Component the name of the site component, if any
+	Site      string
+	Component string
+	// Approval decides whether the changes to the node need to be approved, like for the nodes of its site
+	Approval config.ApprovalType
+}
+
+func NewRemoved(path, identifier string, typ Type) *Removed {
+	return &Removed{
+		baseNode: newBaseNode(nil, path, identifier, typ, nil, config.DeploymentSiteComponent),
+	}
+}
+
+// Parents returns no nodes, as the removed node is not part of a graph
+func (r *Removed) Parents() ([]Node, error) {
+	return nil, nil
+}
+
+// Hash returns an empty hash, as there is no configuration left to compute it from
+func (r *Removed) Hash() (string, error) {
+	return "", nil
+}
@@ -35,5 +35,28 @@ func (s *Site) Hash() (string, error) {
 		hashes = append(hashes, h)
 	}
 
+	// Only include the site level import and moved blocks when set to keep the hash stable for existing sites
+	if len(s.SiteConfig.Imports) > 0 || len(s.SiteConfig.Moved) > 0 {
+		h, err := utils.ComputeHash(struct {
+			Imports []config.ImportConfig `json:"imports"`
+			Moved   []config.MovedConfig  `json:"moved"`
+		}{
+			Imports: s.SiteConfig.Imports,
+			Moved:   s.SiteConfig.Moved,
+		})
+		if err != nil {
+			return "", err
+		}
+		hashes = append(hashes, h)
+	}
+
+	extraHash, err := hashExtraTerraform(s.ProjectConfig.Global.ExtraTerraform, s.SiteConfig.ExtraTerraform)
+	if err != nil {
+		return "", err
+	}
+	if extraHash != "" {
+		hashes = append(hashes, extraHash)
+	}
+
 	return utils.ComputeHash(hashes)
 }
@@ -78,6 +78,12 @@ func (h *JsonFileHandler) Fetch(_ context.Context, n graph.Node) (string, error)
 	case graph.ProjectType:
 		return "", nil
 	case graph.SiteType:
+		// The hash of the site also covers site level settings, like imports and extra terraform, so it is stored as
+		// a whole. Hash files written before that only have the hashes of the nested components.
+		if h, ok := (*hashes)[n.Identifier()]; ok {
+			return h, nil
+		}
+
 		s := n.(*graph.Site)
 		graph.SortSiteComponentNodes(s.NestedNodes)
 
@@ -114,6 +120,10 @@ func (h *JsonFileHandler) Store(_ context.Context, n graph.Node) error {
 				return err
 			}
 		}
+		(*hashes)[n.Identifier()], err = n.Hash()
+		if err != nil {
+			return err
+		}
 	case graph.SiteComponentType:
 		(*hashes)[n.Identifier()], err = n.Hash()
 		if err != nil {
@@ -0,0 +1,63 @@
+package hash
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+)
+
+func newTestSite(cfg config.MachConfig, siteConfig config.SiteConfig) *graph.Site {
+	siteConfig.Identifier = "my-site"
+	site := graph.NewSite(nil, "main/my-site", "my-site", config.DeploymentSite, nil, cfg, siteConfig)
+	site.NestedNodes = []*graph.SiteComponent{
+		graph.NewSiteComponent(nil, "main/my-site", "my-site/api", config.DeploymentSite, site, cfg,
+			siteConfig, config.SiteComponentConfig{
+				Name:       "api",
+				Definition: &config.ComponentConfig{Name: "api", Source: "git::https://example.com/api.git"},
+			}),
+	}
+	return site
+}
+
+func TestJsonFileHandlerSite(t *testing.T) {
+	locals := []config.ExtraTerraform{"locals {\n  a = 1\n}\n"}
+	tests := map[string]struct {
+		global config.GlobalConfig
+		site   config.SiteConfig
+	}{
+		"components only": {},
+		"imports": {
+			site: config.SiteConfig{Imports: []config.ImportConfig{{To: "aws_s3_bucket.a", ID: "bucket"}}},
+		},
+		"moved": {
+			site: config.SiteConfig{Moved: []config.MovedConfig{{From: "aws_s3_bucket.a", To: "aws_s3_bucket.b"}}},
+		},
+		"global extra terraform": {global: config.GlobalConfig{ExtraTerraform: locals}},
+		"site extra terraform":   {site: config.SiteConfig{ExtraTerraform: locals}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			h := NewJsonFileHandler(filepath.Join(t.TempDir(), "hashes.json"))
+			site := newTestSite(config.MachConfig{Global: tc.global}, tc.site)
+
+			require.NoError(t, h.Store(ctx, site))
+
+			expected, err := site.Hash()
+			require.NoError(t, err)
+			stored, err := h.Fetch(ctx, site)
+			require.NoError(t, err)
+			assert.Equal(t, expected, stored)
+
+			stored, err = h.Fetch(ctx, site.NestedNodes[0])
+			require.NoError(t, err)
+			assert.NotEmpty(t, stored)
+		})
+	}
+}
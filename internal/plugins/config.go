@@ -13,29 +13,39 @@ type PluginConfig struct {
 	Source  string
 	Version string
 	Replace string
+
+	// Registry overrides the registry endpoint used to download the plugin
+	Registry string
+	// Mirror is a local directory containing plugin binaries. When set the plugin is only resolved from the mirror
+	Mirror string
 }
 
 func (pc PluginConfig) name() string {
 	return filepath.Base(pc.Source)
 }
 
-func (pc PluginConfig) executableName() string {
+func (pc PluginConfig) executableName(platform Platform) string {
 	executableName := fmt.Sprintf("mach-composer-plugin-%s_v%s", pc.name(), pc.Version)
 
-	if runtime.GOOS == "windows" {
+	if platform.OS == "windows" {
 		executableName += ".exe"
 	}
 
 	return executableName
 }
 
+// pluginsDir returns the directory in which all downloaded plugins are stored
+func pluginsDir() string {
+	return path.Join(xdg.ConfigHome, "mach-composer", "plugins")
+}
+
 func (pc PluginConfig) path() string {
 	if pc.Replace != "" {
 		return pc.Replace
 	}
 
 	p := path.Join(
-		xdg.ConfigHome, "mach-composer", "plugins", pc.Source, pc.Version,
+		pluginsDir(), pc.Source, pc.Version,
 		fmt.Sprintf("%s_%s", runtime.GOOS, runtime.GOARCH),
 		pc.name())
 
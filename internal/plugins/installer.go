@@ -0,0 +1,115 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+)
+
+// IsInstalled returns true if the plugin binary for the current platform is available locally
+func (pc PluginConfig) IsInstalled() bool {
+	_, err := os.Stat(pc.path())
+	return err == nil
+}
+
+// InstalledVersions returns the versions of the plugin with the given source that are available in the plugin
+// directory, ordered by name
+func InstalledVersions(source string) ([]string, error) {
+	entries, err := os.ReadDir(path.Join(pluginsDir(), source))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			versions = append(versions, entry.Name())
+		}
+	}
+	sort.Strings(versions)
+	return versions, nil
+}
+
+// InstallPlugin downloads the plugin if it is not yet available and verifies it against the lock file
+func InstallPlugin(cfg PluginConfig, lock *LockFile) error {
+	if cfg.Replace != "" {
+		log.Info().Msgf("Skipping plugin %s since it is replaced by %s", cfg.Source, cfg.Replace)
+		return nil
+	}
+
+	if _, err := resolvePlugin(cfg, lock); err != nil {
+		return fmt.Errorf("failed to install plugin %s: %w", cfg.Source, err)
+	}
+	return nil
+}
+
+// VerifyPlugin checks the installed plugin against the lock file. Unlike loading a plugin this does not add missing
+// entries to the lock file, but reports them as an error
+func VerifyPlugin(cfg PluginConfig, lock *LockFile) error {
+	if cfg.Replace != "" {
+		return nil
+	}
+
+	if !cfg.IsInstalled() {
+		return fmt.Errorf("plugin %s %s is not installed", cfg.Source, cfg.Version)
+	}
+
+	locked, ok := lock.Get(cfg.Source)
+	if !ok {
+		return fmt.Errorf("plugin %s is not part of the lock file", cfg.Source)
+	}
+	if _, ok := locked.Hashes[CurrentPlatform().String()]; !ok {
+		return fmt.Errorf("plugin %s has no checksum for %s in the lock file", cfg.Source, CurrentPlatform())
+	}
+
+	checksum, err := getPluginChecksum(cfg.path())
+	if err != nil {
+		return err
+	}
+
+	return lock.Verify(cfg, CurrentPlatform(), checksum)
+}
+
+// CleanPlugins removes all installed versions of the given plugins that are not the configured version. It returns
+// the removed directories. When dryRun is set nothing is removed.
+func CleanPlugins(configs map[string]PluginConfig, dryRun bool) ([]string, error) {
+	var removed []string
+
+	for _, cfg := range configs {
+		if cfg.Replace != "" {
+			continue
+		}
+
+		versions, err := InstalledVersions(cfg.Source)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, version := range versions {
+			if version == cfg.Version {
+				continue
+			}
+
+			dir := path.Join(pluginsDir(), cfg.Source, version)
+			removed = append(removed, dir)
+			if dryRun {
+				continue
+			}
+
+			log.Debug().Msgf("Removing %s", dir)
+			if err := os.RemoveAll(dir); err != nil {
+				return nil, fmt.Errorf("failed to remove %s: %w", dir, err)
+			}
+		}
+	}
+
+	sort.Strings(removed)
+	return removed, nil
+}
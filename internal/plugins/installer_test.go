@@ -0,0 +1,77 @@
+package plugins
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupPluginsDir(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+}
+
+func TestInstallAndVerifyPlugin(t *testing.T) {
+	setupPluginsDir(t)
+	newTestRegistry(t, "test", "0.1.0", func(p Platform) []byte {
+		return []byte("binary")
+	})
+
+	cfg := PluginConfig{Source: "mach-composer/test", Version: "0.1.0"}
+	lock := NewLockFile("unused")
+
+	assert.ErrorContains(t, VerifyPlugin(cfg, lock), "is not installed")
+
+	require.NoError(t, InstallPlugin(cfg, lock))
+	assert.True(t, cfg.IsInstalled())
+	require.NoError(t, VerifyPlugin(cfg, lock))
+
+	// Verification fails when the installed binary is changed afterwards
+	require.NoError(t, os.WriteFile(cfg.path(), []byte("changed"), 0700))
+	var mismatch *ChecksumMismatchError
+	assert.ErrorAs(t, VerifyPlugin(cfg, lock), &mismatch)
+}
+
+func TestVerifyPluginNotLocked(t *testing.T) {
+	setupPluginsDir(t)
+	newTestRegistry(t, "test", "0.1.0", func(p Platform) []byte {
+		return []byte("binary")
+	})
+
+	cfg := PluginConfig{Source: "mach-composer/test", Version: "0.1.0"}
+	require.NoError(t, InstallPlugin(cfg, nil))
+
+	assert.ErrorContains(t, VerifyPlugin(cfg, NewLockFile("unused")), "is not part of the lock file")
+}
+
+func TestCleanPlugins(t *testing.T) {
+	setupPluginsDir(t)
+
+	for _, version := range []string{"0.1.0", "0.2.0", "0.3.0"} {
+		require.NoError(t, os.MkdirAll(path.Join(pluginsDir(), "mach-composer/test", version), 0700))
+	}
+
+	configs := map[string]PluginConfig{
+		"test": {Source: "mach-composer/test", Version: "0.2.0"},
+	}
+
+	removed, err := CleanPlugins(configs, true)
+	require.NoError(t, err)
+	assert.Len(t, removed, 2)
+
+	versions, err := InstalledVersions("mach-composer/test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0.1.0", "0.2.0", "0.3.0"}, versions)
+
+	_, err = CleanPlugins(configs, false)
+	require.NoError(t, err)
+
+	versions, err = InstalledVersions("mach-composer/test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0.2.0"}, versions)
+}
@@ -0,0 +1,253 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+	"github.com/rs/zerolog/log"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// LockFileName is the name of the plugin lock file, which is stored next to the configuration file
+const LockFileName = ".mach-composer.lock.hcl"
+
+const lockFileHeader = `# This file is maintained automatically by "mach-composer plugins lock".
+# Manual edits may be lost in future updates.
+`
+
+type ChecksumMismatchError struct {
+	Source   string
+	Version  string
+	Platform Platform
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf(
+		"checksum of plugin %s %s (%s) does not match the lock file: expected %s, got %s",
+		e.Source, e.Version, e.Platform, e.Expected, e.Actual,
+	)
+}
+
+// LockedPlugin is a single plugin entry in the lock file. Hashes are keyed by platform, e.g. `linux_amd64`
+type LockedPlugin struct {
+	Source  string            `hcl:"source,label"`
+	Version string            `hcl:"version"`
+	Hashes  map[string]string `hcl:"hashes"`
+}
+
+type lockFileContent struct {
+	Plugins []*LockedPlugin `hcl:"plugin,block"`
+}
+
+// LockFile records the version and the SHA-256 checksums per platform of every plugin that is used. Checksums are
+// verified every time a plugin is loaded.
+type LockFile struct {
+	filename string
+	plugins  map[string]*LockedPlugin
+	dirty    bool
+}
+
+// NewLockFile returns an empty lock file that will be written to the given filename
+func NewLockFile(filename string) *LockFile {
+	return &LockFile{
+		filename: filename,
+		plugins:  make(map[string]*LockedPlugin),
+	}
+}
+
+// LoadLockFile reads the lock file from the given filename. If the file does not exist an empty lock file is returned
+func LoadLockFile(filename string) (*LockFile, error) {
+	lf := NewLockFile(filename)
+
+	body, err := utils.AFS.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return lf, nil
+		}
+		return nil, fmt.Errorf("failed to read lock file: %w", err)
+	}
+
+	file, diags := hclparse.NewParser().ParseHCL(body, filename)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse lock file %s: %w", filename, diags)
+	}
+
+	content := &lockFileContent{}
+	if diags := gohcl.DecodeBody(file.Body, nil, content); diags.HasErrors() {
+		return nil, fmt.Errorf("failed to decode lock file %s: %w", filename, diags)
+	}
+
+	for _, p := range content.Plugins {
+		if p.Hashes == nil {
+			p.Hashes = make(map[string]string)
+		}
+		lf.plugins[p.Source] = p
+	}
+
+	return lf, nil
+}
+
+func (l *LockFile) Filename() string {
+	return l.filename
+}
+
+// Get returns the locked plugin for the given source
+func (l *LockFile) Get(source string) (*LockedPlugin, bool) {
+	p, ok := l.plugins[source]
+	return p, ok
+}
+
+// All returns all locked plugins, ordered by source
+func (l *LockFile) All() []*LockedPlugin {
+	keys := make([]string, 0, len(l.plugins))
+	for k := range l.plugins {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]*LockedPlugin, len(keys))
+	for i, k := range keys {
+		result[i] = l.plugins[k]
+	}
+	return result
+}
+
+// Set adds or replaces the locked plugin
+func (l *LockFile) Set(p *LockedPlugin) {
+	l.plugins[p.Source] = p
+	l.dirty = true
+}
+
+// Verify checks the checksum of the given plugin against the lock file. Plugins or platforms that are not yet part of
+// the lock file are added, so they are verified on subsequent loads.
+func (l *LockFile) Verify(cfg PluginConfig, platform Platform, checksum string) error {
+	locked, ok := l.plugins[cfg.Source]
+	if !ok {
+		log.Info().Msgf("Adding plugin %s %s to %s", cfg.Source, cfg.Version, l.filename)
+		l.Set(&LockedPlugin{
+			Source:  cfg.Source,
+			Version: cfg.Version,
+			Hashes:  map[string]string{platform.String(): checksum},
+		})
+		return nil
+	}
+
+	if locked.Version != cfg.Version {
+		return fmt.Errorf(
+			"plugin %s is locked to version %s but version %s is configured. "+
+				"Run `mach-composer plugins lock` to update the lock file",
+			cfg.Source, locked.Version, cfg.Version,
+		)
+	}
+
+	expected, ok := locked.Hashes[platform.String()]
+	if !ok {
+		log.Warn().Msgf("No checksum for plugin %s on %s in %s; adding it", cfg.Source, platform, l.filename)
+		locked.Hashes[platform.String()] = checksum
+		l.dirty = true
+		return nil
+	}
+
+	if expected != checksum {
+		return &ChecksumMismatchError{
+			Source:   cfg.Source,
+			Version:  cfg.Version,
+			Platform: platform,
+			Expected: expected,
+			Actual:   checksum,
+		}
+	}
+
+	return nil
+}
+
+// Save writes the lock file to disk if it has been changed
+func (l *LockFile) Save() error {
+	if !l.dirty {
+		return nil
+	}
+
+	f := hclwrite.NewEmptyFile()
+	body := f.Body()
+
+	for i, p := range l.All() {
+		if i > 0 {
+			body.AppendNewline()
+		}
+		block := body.AppendNewBlock("plugin", []string{p.Source})
+		block.Body().SetAttributeValue("version", cty.StringVal(p.Version))
+
+		hashes := make(map[string]cty.Value, len(p.Hashes))
+		for platform, h := range p.Hashes {
+			hashes[platform] = cty.StringVal(h)
+		}
+		if len(hashes) > 0 {
+			block.Body().SetAttributeValue("hashes", cty.ObjectVal(hashes))
+		} else {
+			block.Body().SetAttributeValue("hashes", cty.EmptyObjectVal)
+		}
+	}
+
+	content := append([]byte(lockFileHeader+"\n"), hclwrite.Format(f.Bytes())...)
+	if err := utils.AFS.WriteFile(l.filename, content, 0644); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	l.dirty = false
+
+	return nil
+}
+
+// UpdateLockFile downloads the configured plugins for the given platforms and records their checksums in the lock
+// file. Checksums of other platforms are kept as long as the plugin version did not change, and plugins that are no
+// longer configured are removed.
+func UpdateLockFile(l *LockFile, configs map[string]PluginConfig, platforms []Platform) error {
+	current := make(map[string]*LockedPlugin)
+
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		cfg := configs[name]
+		if cfg.Replace != "" {
+			log.Info().Msgf("Skipping plugin %s since it is replaced by %s", name, cfg.Replace)
+			continue
+		}
+
+		entry := &LockedPlugin{
+			Source:  cfg.Source,
+			Version: cfg.Version,
+			Hashes:  make(map[string]string),
+		}
+		if existing, ok := l.plugins[cfg.Source]; ok && existing.Version == cfg.Version {
+			for platform, h := range existing.Hashes {
+				entry.Hashes[platform] = h
+			}
+		}
+
+		for _, platform := range platforms {
+			log.Info().Msgf("Fetching checksum for plugin %s %s (%s)", cfg.Source, cfg.Version, platform)
+			checksum, err := FetchPluginChecksum(cfg, platform)
+			if err != nil {
+				return err
+			}
+			entry.Hashes[platform.String()] = checksum
+		}
+
+		current[cfg.Source] = entry
+	}
+
+	l.plugins = current
+	l.dirty = true
+	return nil
+}
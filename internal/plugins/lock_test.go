@@ -0,0 +1,185 @@
+package plugins
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRegistry starts a registry stand-in that serves a zip archive with the given plugin binary content for
+// every requested platform
+func newTestRegistry(t *testing.T, name, version string, content func(platform Platform) []byte) {
+	t.Helper()
+
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == fmt.Sprintf("/v1/plugins/mach-composer/%s", name) {
+			err := json.NewEncoder(w).Encode(&registryResponse{
+				URL: fmt.Sprintf("%s/download/%s_%s/plugin.zip", ts.URL, r.URL.Query().Get("os"), r.URL.Query().Get("arch")),
+			})
+			require.NoError(t, err)
+			return
+		}
+
+		p, err := ParsePlatform(path.Dir(strings.TrimPrefix(r.URL.Path, "/download/")))
+		require.NoError(t, err)
+
+		buf := &bytes.Buffer{}
+		zw := zip.NewWriter(buf)
+		cfg := PluginConfig{Source: "mach-composer/" + name, Version: version}
+		f, err := zw.Create(cfg.executableName(p))
+		require.NoError(t, err)
+		_, err = f.Write(content(p))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+
+		w.Header().Set("Content-Type", "application/zip")
+		_, _ = w.Write(buf.Bytes())
+	}))
+	t.Cleanup(ts.Close)
+
+	endpoint := registryEndpoint
+	t.Cleanup(func() { registryEndpoint = endpoint })
+	registryEndpoint = ts.URL
+}
+
+func checksumOf(content []byte) string {
+	h := sha256.Sum256(content)
+	return checksumPrefix + hex.EncodeToString(h[:])
+}
+
+func TestLockFileSaveAndLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), LockFileName)
+
+	lf := NewLockFile(filename)
+	lf.Set(&LockedPlugin{
+		Source:  "mach-composer/aws",
+		Version: "0.1.0",
+		Hashes: map[string]string{
+			"linux_amd64":  "sha256:abc",
+			"darwin_arm64": "sha256:def",
+		},
+	})
+	require.NoError(t, lf.Save())
+
+	loaded, err := LoadLockFile(filename)
+	require.NoError(t, err)
+
+	p, ok := loaded.Get("mach-composer/aws")
+	require.True(t, ok)
+	assert.Equal(t, "0.1.0", p.Version)
+	assert.Equal(t, "sha256:abc", p.Hashes["linux_amd64"])
+	assert.Equal(t, "sha256:def", p.Hashes["darwin_arm64"])
+}
+
+func TestLoadLockFileMissing(t *testing.T) {
+	lf, err := LoadLockFile(filepath.Join(t.TempDir(), LockFileName))
+	require.NoError(t, err)
+	assert.Empty(t, lf.All())
+}
+
+func TestLockFileVerify(t *testing.T) {
+	platform := Platform{OS: "linux", Arch: "amd64"}
+	cfg := PluginConfig{Source: "mach-composer/aws", Version: "0.1.0"}
+
+	lf := NewLockFile("unused")
+
+	// Unknown plugins are added to the lock file
+	require.NoError(t, lf.Verify(cfg, platform, "sha256:abc"))
+	require.NoError(t, lf.Verify(cfg, platform, "sha256:abc"))
+
+	err := lf.Verify(cfg, platform, "sha256:tampered")
+	var mismatch *ChecksumMismatchError
+	require.True(t, errors.As(err, &mismatch))
+	assert.Equal(t, "sha256:abc", mismatch.Expected)
+
+	// Other platforms are added for the same version
+	require.NoError(t, lf.Verify(cfg, Platform{OS: "darwin", Arch: "arm64"}, "sha256:def"))
+
+	err = lf.Verify(PluginConfig{Source: "mach-composer/aws", Version: "0.2.0"}, platform, "sha256:abc")
+	assert.ErrorContains(t, err, "locked to version 0.1.0")
+}
+
+func TestUpdateLockFile(t *testing.T) {
+	newTestRegistry(t, "test", "0.1.0", func(p Platform) []byte {
+		return []byte("binary for " + p.String())
+	})
+
+	lf := NewLockFile(filepath.Join(t.TempDir(), LockFileName))
+	lf.Set(&LockedPlugin{Source: "mach-composer/removed", Version: "1.0.0", Hashes: map[string]string{}})
+
+	platforms := []Platform{{OS: "linux", Arch: "amd64"}, {OS: "darwin", Arch: "arm64"}}
+	err := UpdateLockFile(lf, map[string]PluginConfig{
+		"test":     {Source: "mach-composer/test", Version: "0.1.0"},
+		"replaced": {Source: "mach-composer/replaced", Version: "0.1.0", Replace: "/tmp/plugin"},
+	}, platforms)
+	require.NoError(t, err)
+
+	_, ok := lf.Get("mach-composer/removed")
+	assert.False(t, ok)
+	_, ok = lf.Get("mach-composer/replaced")
+	assert.False(t, ok)
+
+	p, ok := lf.Get("mach-composer/test")
+	require.True(t, ok)
+	assert.Equal(t, checksumOf([]byte("binary for linux_amd64")), p.Hashes["linux_amd64"])
+	assert.Equal(t, checksumOf([]byte("binary for darwin_arm64")), p.Hashes["darwin_arm64"])
+}
+
+func TestResolvePluginRejectsTamperedDownload(t *testing.T) {
+	setupPluginsDir(t)
+
+	newTestRegistry(t, "test", "0.1.0", func(p Platform) []byte {
+		return []byte("tampered binary")
+	})
+
+	cfg := PluginConfig{Source: "mach-composer/test", Version: "0.1.0"}
+	lf := NewLockFile("unused")
+	lf.Set(&LockedPlugin{
+		Source:  cfg.Source,
+		Version: cfg.Version,
+		Hashes:  map[string]string{CurrentPlatform().String(): checksumOf([]byte("original binary"))},
+	})
+
+	_, err := resolvePlugin(cfg, lf)
+	var mismatch *ChecksumMismatchError
+	require.True(t, errors.As(err, &mismatch), err)
+
+	_, err = os.Stat(cfg.path())
+	assert.True(t, os.IsNotExist(err), "tampered plugin should be removed")
+}
+
+func TestResolvePluginVerifiesChecksum(t *testing.T) {
+	setupPluginsDir(t)
+
+	newTestRegistry(t, "test", "0.1.0", func(p Platform) []byte {
+		return []byte("original binary")
+	})
+
+	cfg := PluginConfig{Source: "mach-composer/test", Version: "0.1.0"}
+	lf := NewLockFile("unused")
+
+	executable, err := resolvePlugin(cfg, lf)
+	require.NoError(t, err)
+
+	expected := sha256.Sum256([]byte("original binary"))
+	assert.Equal(t, expected[:], executable.Checksum)
+
+	p, ok := lf.Get(cfg.Source)
+	require.True(t, ok)
+	assert.Equal(t, checksumOf([]byte("original binary")), p.Hashes[CurrentPlatform().String()])
+}
@@ -0,0 +1,72 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/rs/zerolog/log"
+)
+
+// mirrorPath returns the location of the plugin executable in a filesystem mirror. The layout is
+// `<mirror>/<source>/<version>/<os>_<arch>/<executable>`, for example
+// `mirror/mach-composer/aws/0.1.0/linux_amd64/mach-composer-plugin-aws_v0.1.0`
+func mirrorPath(dir string, pluginCfg PluginConfig, platform Platform) string {
+	return path.Join(dir, pluginCfg.Source, pluginCfg.Version, platform.String(), pluginCfg.executableName(platform))
+}
+
+// fetchPluginFromMirror copies the plugin executable from the filesystem mirror to the given directory
+func fetchPluginFromMirror(pluginCfg PluginConfig, platform Platform, dst string) (string, error) {
+	src := mirrorPath(pluginCfg.Mirror, pluginCfg, platform)
+	if _, err := os.Stat(src); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("plugin %s %s (%s) not found in mirror %s", pluginCfg.Source, pluginCfg.Version, platform, pluginCfg.Mirror)
+		}
+		return "", err
+	}
+
+	log.Debug().Msgf("Using plugin %s from mirror %s", pluginCfg.Source, pluginCfg.Mirror)
+
+	filename := path.Join(dst, pluginCfg.executableName(platform))
+	if err := copyExecutable(src, filename); err != nil {
+		return "", fmt.Errorf("failed to copy plugin from mirror: %w", err)
+	}
+	return filename, nil
+}
+
+// MirrorPlugin downloads the plugin for the given platform from the registry and stores it in the mirror directory
+func MirrorPlugin(pluginCfg PluginConfig, platform Platform, dir string) error {
+	if err := validateSource(pluginCfg.Source); err != nil {
+		return err
+	}
+
+	// Always download from the registry, even if a mirror is configured
+	pluginCfg.Mirror = ""
+
+	tempDir, err := os.MkdirTemp("", "mcp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary dir: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	filename, err := fetchPlugin(pluginCfg, platform, tempDir)
+	if err != nil {
+		return fmt.Errorf("failed to download plugin %s for %s: %w", pluginCfg.name(), platform, err)
+	}
+
+	target := mirrorPath(dir, pluginCfg, platform)
+	if err := os.MkdirAll(path.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	return copyExecutable(filename, target)
+}
+
+func copyExecutable(src, dst string) error {
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, content, 0755)
+}
@@ -0,0 +1,58 @@
+package plugins
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirrorPlugin(t *testing.T) {
+	setupPluginsDir(t)
+	newTestRegistry(t, "test", "0.1.0", func(p Platform) []byte {
+		return []byte("binary for " + p.String())
+	})
+
+	mirror := t.TempDir()
+	cfg := PluginConfig{Source: "mach-composer/test", Version: "0.1.0"}
+
+	linux := Platform{OS: "linux", Arch: "amd64"}
+	windows := Platform{OS: "windows", Arch: "amd64"}
+	require.NoError(t, MirrorPlugin(cfg, linux, mirror))
+	require.NoError(t, MirrorPlugin(cfg, windows, mirror))
+
+	content, err := os.ReadFile(mirror + "/mach-composer/test/0.1.0/linux_amd64/mach-composer-plugin-test_v0.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "binary for linux_amd64", string(content))
+
+	_, err = os.Stat(mirror + "/mach-composer/test/0.1.0/windows_amd64/mach-composer-plugin-test_v0.1.0.exe")
+	assert.NoError(t, err)
+}
+
+func TestResolvePluginFromMirror(t *testing.T) {
+	setupPluginsDir(t)
+	newTestRegistry(t, "test", "0.1.0", func(p Platform) []byte {
+		return []byte("binary")
+	})
+
+	mirror := t.TempDir()
+	cfg := PluginConfig{Source: "mach-composer/test", Version: "0.1.0"}
+	require.NoError(t, MirrorPlugin(cfg, CurrentPlatform(), mirror))
+
+	// Make sure the registry is not used anymore
+	registryEndpoint = "http://127.0.0.1:0"
+
+	cfg.Mirror = mirror
+	executable, err := resolvePlugin(cfg, nil)
+	require.NoError(t, err)
+	assert.Equal(t, cfg.path(), executable.Path)
+}
+
+func TestResolvePluginMissingFromMirror(t *testing.T) {
+	setupPluginsDir(t)
+
+	cfg := PluginConfig{Source: "mach-composer/test", Version: "0.1.0", Mirror: t.TempDir()}
+	_, err := resolvePlugin(cfg, nil)
+	assert.ErrorContains(t, err, "not found in mirror")
+}
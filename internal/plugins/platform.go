@@ -0,0 +1,31 @@
+package plugins
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Platform is an operating system and architecture combination for which plugin binaries are distributed
+type Platform struct {
+	OS   string
+	Arch string
+}
+
+// CurrentPlatform returns the platform mach-composer is currently running on
+func CurrentPlatform() Platform {
+	return Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+// ParsePlatform parses a platform in the form of `os_arch`, for example `linux_amd64`
+func ParsePlatform(value string) (Platform, error) {
+	parts := strings.SplitN(value, "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Platform{}, fmt.Errorf("invalid platform %s: expected the form os_arch, for example linux_amd64", value)
+	}
+	return Platform{OS: parts[0], Arch: parts[1]}, nil
+}
+
+func (p Platform) String() string {
+	return fmt.Sprintf("%s_%s", p.OS, p.Arch)
+}
@@ -2,6 +2,7 @@ package plugins
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"github.com/hashicorp/go-plugin"
 	protocolv1 "github.com/mach-composer/mach-composer-plugin-sdk/protocol"
@@ -9,7 +10,6 @@ import (
 	protocolv2 "github.com/mach-composer/mach-composer-plugin-sdk/v2/protocol"
 	schemav2 "github.com/mach-composer/mach-composer-plugin-sdk/v2/schema"
 	"github.com/rs/zerolog/log"
-	"hash/crc32"
 )
 
 type PluginHandler struct {
@@ -19,6 +19,10 @@ type PluginHandler struct {
 	client    *plugin.Client
 	isRunning bool
 	Config    PluginConfig
+	lock      *LockFile
+
+	// ProtocolVersion is the plugin protocol version negotiated when the plugin was started
+	ProtocolVersion int
 }
 
 func (p *PluginHandler) Close() {
@@ -44,7 +48,7 @@ func (p *PluginHandler) Start(ctx context.Context) error {
 
 	logger := NewHCLogAdapter(log.Logger)
 
-	executable, err := resolvePlugin(p.Config)
+	executable, err := resolvePlugin(p.Config, p.lock)
 	if err != nil {
 		return err
 	}
@@ -59,7 +63,7 @@ func (p *PluginHandler) Start(ctx context.Context) error {
 		Cmd:              executable.command(),
 		Logger:           logger,
 		SecureConfig: &plugin.SecureConfig{
-			Hash:     crc32.NewIEEE(),
+			Hash:     sha256.New(),
 			Checksum: executable.Checksum,
 		},
 	})
@@ -102,6 +106,7 @@ func (p *PluginHandler) Start(ctx context.Context) error {
 	default:
 		return fmt.Errorf("incompatible protocol version %d. Try upgrading your mach-composer binary to the latest version", protocolVersion)
 	}
+	p.ProtocolVersion = protocolVersion
 	p.isRunning = true
 
 	return nil
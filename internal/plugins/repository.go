@@ -30,6 +30,7 @@ func (e *PluginError) Error() string {
 type PluginRepository struct {
 	handlers map[string]*PluginHandler
 	schemas  map[string]schemav2.ValidationSchema
+	lock     *LockFile
 }
 
 func NewPluginRepository() *PluginRepository {
@@ -39,27 +40,50 @@ func NewPluginRepository() *PluginRepository {
 	}
 }
 
+// SetLockFile sets the lock file used to verify the checksums of the plugins when they are loaded
+func (p *PluginRepository) SetLockFile(lock *LockFile) {
+	p.lock = lock
+}
+
+// SaveLockFile writes any changes to the lock file made while loading the plugins
+func (p *PluginRepository) SaveLockFile() error {
+	if p.lock == nil {
+		return nil
+	}
+	return p.lock.Save()
+}
+
 // Close kills all the running handlers
 func (p *PluginRepository) Close() {
 	for _, rp := range p.handlers {
-		rp.Close()
+		if rp.isRunning {
+			rp.Close()
+		}
 	}
 }
 
-// All returns all the plugin names in the repository, ordered by the plugin name
+// All returns all the started plugins in the repository, ordered by the plugin name. Plugins that are registered but
+// not referenced by the configuration are not started and therefore not returned
 func (p *PluginRepository) All() []*PluginHandler {
-	result := make([]*PluginHandler, len(p.handlers))
-	for i, key := range pie.Sort(pie.Keys(p.handlers)) {
-		result[i] = p.handlers[key]
+	var result []*PluginHandler
+	for _, key := range pie.Sort(pie.Keys(p.handlers)) {
+		if p.handlers[key].isRunning {
+			result = append(result, p.handlers[key])
+		}
 	}
 	return result
 }
 
+// Registered returns the names of all registered plugins, whether they are started or not
+func (p *PluginRepository) Registered() []string {
+	return pie.Sort(pie.Keys(p.handlers))
+}
+
 func (p *PluginRepository) Names(names ...string) []PluginHandler {
 	var result []PluginHandler
 
 	for _, key := range pie.Sort(pie.Keys(p.handlers)) {
-		if !pie.Contains(names, key) {
+		if !pie.Contains(names, key) || !p.handlers[key].isRunning {
 			continue
 		}
 
@@ -81,8 +105,9 @@ func (p *PluginRepository) Add(name string, machComposerPlugin schemav2.MachComp
 	return nil
 }
 
-// Get returns the plugin from the repository
-func (p *PluginRepository) Get(name string) (*PluginHandler, error) {
+// Get returns the plugin from the repository. A registered plugin that has not been started yet is started first, and
+// the lock file is saved again to keep the entries added while resolving it
+func (p *PluginRepository) Get(ctx context.Context, name string) (*PluginHandler, error) {
 	if name == "" {
 		panic("handler name is empty") // this should never happen
 	}
@@ -90,29 +115,62 @@ func (p *PluginRepository) Get(name string) (*PluginHandler, error) {
 	if !ok {
 		return nil, &PluginNotFoundError{name: name}
 	}
+	if !handler.isRunning {
+		log.Ctx(ctx).Debug().Msgf("Starting plugin %s on first use", name)
+		if err := handler.Start(ctx); err != nil {
+			return nil, err
+		}
+		if err := p.SaveLockFile(); err != nil {
+			return nil, err
+		}
+	}
 	return handler, nil
 }
 
-// LoadPlugin will load the plugin with the given name and Start it
-func (p *PluginRepository) LoadPlugin(ctx context.Context, name string, config PluginConfig) error {
+// RegisterPlugin adds the plugin with the given name to the repository without starting it. Use StartPlugin or Get to
+// start it once it is needed
+func (p *PluginRepository) RegisterPlugin(name string, config PluginConfig) error {
 	if _, ok := p.handlers[name]; ok {
 		return fmt.Errorf("handler %s is already loaded", name)
 	}
 
-	handler := &PluginHandler{
+	p.handlers[name] = &PluginHandler{
 		Name:   name,
 		Config: config,
+		lock:   p.lock,
 	}
-	p.handlers[name] = handler
+	return nil
+}
 
-	if err := handler.Start(ctx); err != nil {
-		return err
+// StartPlugin starts the registered plugin with the given name
+func (p *PluginRepository) StartPlugin(ctx context.Context, name string) error {
+	handler, ok := p.handlers[name]
+	if !ok {
+		return &PluginNotFoundError{name: name}
+	}
+	return handler.Start(ctx)
+}
+
+// StartAll starts all registered plugins
+func (p *PluginRepository) StartAll(ctx context.Context) error {
+	for _, name := range p.Registered() {
+		if err := p.StartPlugin(ctx, name); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (p *PluginRepository) loadSchema(name string) (*schemav2.ValidationSchema, error) {
-	plugin, err := p.Get(name)
+// LoadPlugin will load the plugin with the given name and Start it
+func (p *PluginRepository) LoadPlugin(ctx context.Context, name string, config PluginConfig) error {
+	if err := p.RegisterPlugin(name, config); err != nil {
+		return err
+	}
+	return p.StartPlugin(ctx, name)
+}
+
+func (p *PluginRepository) loadSchema(ctx context.Context, name string) (*schemav2.ValidationSchema, error) {
+	plugin, err := p.Get(ctx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -128,7 +186,7 @@ func (p *PluginRepository) loadSchema(name string) (*schemav2.ValidationSchema,
 	return schema, nil
 }
 
-func (p *PluginRepository) GetSchema(name string) (*schemav2.ValidationSchema, error) {
+func (p *PluginRepository) GetSchema(ctx context.Context, name string) (*schemav2.ValidationSchema, error) {
 	if name == "" {
 		panic("plugin name is empty") // this should never happen
 	}
@@ -138,7 +196,7 @@ func (p *PluginRepository) GetSchema(name string) (*schemav2.ValidationSchema, e
 		return &schema, nil
 	}
 
-	return p.loadSchema(name)
+	return p.loadSchema(ctx, name)
 }
 
 func (p *PluginRepository) handleError(plugin string, err error) error {
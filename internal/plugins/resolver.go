@@ -1,10 +1,11 @@
 package plugins
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/mach-composer/mach-composer-cli/internal/utils"
-	"hash/crc32"
 	"io"
 	"net/http"
 	"net/url"
@@ -12,7 +13,6 @@ import (
 	"os/exec"
 	"path"
 	"regexp"
-	"runtime"
 	"strings"
 
 	"github.com/hashicorp/go-getter"
@@ -22,6 +22,8 @@ import (
 
 var registryEndpoint = "https://registry.mach.cloud"
 
+const checksumPrefix = "sha256:"
+
 var rePluginName = regexp.MustCompile("(?i)^([a-z0-9_-]+)/([a-z0-9_-]+)$")
 
 type pluginExecutable struct {
@@ -38,34 +40,45 @@ func (p pluginExecutable) command() *exec.Cmd {
 	return exec.Command(p.Path, p.Args...)
 }
 
-func getPluginChecksum(filePath string) ([]byte, error) {
-	h := crc32.NewIEEE()
+// getPluginChecksum returns the SHA-256 checksum of the given file in the form `sha256:<hex>`
+func getPluginChecksum(filePath string) (string, error) {
+	h := sha256.New()
 	file, err := utils.AFS.Open(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get checksum of file: %w", err)
+		return "", fmt.Errorf("failed to get checksum of file: %w", err)
 	}
 	defer file.Close()
 
 	_, err = io.Copy(h, file)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	return h.Sum(nil), nil
+	return checksumPrefix + hex.EncodeToString(h.Sum(nil)), nil
 }
 
-func resolvePlugin(pluginCfg PluginConfig) (*pluginExecutable, error) {
+// decodeChecksum returns the raw bytes of a checksum in the form `sha256:<hex>`
+func decodeChecksum(checksum string) ([]byte, error) {
+	if !strings.HasPrefix(checksum, checksumPrefix) {
+		return nil, fmt.Errorf("unsupported checksum format: %s", checksum)
+	}
+	return hex.DecodeString(strings.TrimPrefix(checksum, checksumPrefix))
+}
+
+func resolvePlugin(pluginCfg PluginConfig, lock *LockFile) (*pluginExecutable, error) {
 
 	if err := validateSource(pluginCfg.Source); err != nil {
 		return nil, err
 	}
 
 	// Download the plugin if we don't have it yet
+	downloaded := false
 	if _, err := os.Stat(pluginCfg.path()); err != nil {
 		log.Debug().Msgf("Plugin %s %s not found, trying to download", pluginCfg.name(), pluginCfg.Version)
 		if err := downloadPlugin(pluginCfg); err != nil {
 			return nil, fmt.Errorf("failed to download plugin %s: %w", pluginCfg.name(), err)
 		}
+		downloaded = true
 	}
 
 	pluginChecksum, err := getPluginChecksum(pluginCfg.path())
@@ -73,70 +86,124 @@ func resolvePlugin(pluginCfg PluginConfig) (*pluginExecutable, error) {
 		return nil, err
 	}
 
+	// Replaced plugins are local development builds, so these are never part of the lock file
+	if lock != nil && pluginCfg.Replace == "" {
+		if err := lock.Verify(pluginCfg, CurrentPlatform(), pluginChecksum); err != nil {
+			if downloaded {
+				_ = os.Remove(pluginCfg.path())
+			}
+			return nil, err
+		}
+	}
+
+	checksum, err := decodeChecksum(pluginChecksum)
+	if err != nil {
+		return nil, err
+	}
+
 	result := &pluginExecutable{
 		Path:     pluginCfg.path(),
-		Checksum: pluginChecksum,
+		Checksum: checksum,
 	}
 	return result, nil
 }
 
-// downloadPlugin queries the registry for the download url, extracts it to a
-// temporary directory and the moves it to the mach-composer plugin directory.
+// downloadPlugin fetches the plugin for the current platform and moves it to the mach-composer plugin directory.
 func downloadPlugin(pluginCfg PluginConfig) error {
 	log.Info().Msgf("Downloading %s (%s)...", pluginCfg.Source, pluginCfg.Version)
 
-	info, err := queryPluginRegistry(pluginCfg)
+	tempDir, err := os.MkdirTemp("", "mcp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary dir: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	filename, err := fetchPlugin(pluginCfg, CurrentPlatform(), tempDir)
 	if err != nil {
 		return err
 	}
 
-	tempDir, err := os.MkdirTemp("", "mcp")
+	target := pluginCfg.path()
+	if err := os.MkdirAll(path.Dir(target), 0700); err != nil {
+		return err
+	}
+
+	if err := os.Rename(filename, pluginCfg.path()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// fetchPlugin queries the registry for the download url of the given platform and extracts it to the given
+// directory. It returns the path to the plugin executable.
+func fetchPlugin(pluginCfg PluginConfig, platform Platform, dst string) (string, error) {
+	if pluginCfg.Mirror != "" {
+		return fetchPluginFromMirror(pluginCfg, platform, dst)
+	}
+
+	info, err := queryPluginRegistry(pluginCfg, platform)
 	if err != nil {
-		return fmt.Errorf("failed to create temporary dir: %w", err)
+		return "", err
 	}
-	defer os.RemoveAll(tempDir)
 
 	client := getter.Client{
 		DisableSymlinks: true,
 		Src:             info.URL,
-		Dst:             tempDir,
+		Dst:             dst,
 		Mode:            getter.ClientModeDir,
 	}
 	if err := client.Get(); err != nil {
-		return err
+		return "", err
 	}
 
-	filename := path.Join(tempDir, pluginCfg.executableName())
+	filename := path.Join(dst, pluginCfg.executableName(platform))
 
 	if _, err := os.Stat(filename); err != nil {
-		return err
+		return "", err
 	}
 
-	target := pluginCfg.path()
-	if err := os.MkdirAll(path.Dir(target), 0700); err != nil {
-		return err
+	return filename, nil
+}
+
+// FetchPluginChecksum downloads the plugin for the given platform to a temporary directory and returns its checksum
+func FetchPluginChecksum(pluginCfg PluginConfig, platform Platform) (string, error) {
+	if err := validateSource(pluginCfg.Source); err != nil {
+		return "", err
 	}
 
-	if err := os.Rename(filename, pluginCfg.path()); err != nil {
-		return err
+	tempDir, err := os.MkdirTemp("", "mcp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary dir: %w", err)
 	}
+	defer os.RemoveAll(tempDir)
 
-	return nil
+	filename, err := fetchPlugin(pluginCfg, platform, tempDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to download plugin %s for %s: %w", pluginCfg.name(), platform, err)
+	}
+
+	return getPluginChecksum(filename)
 }
 
-func queryPluginRegistry(pluginCfg PluginConfig) (*registryResponse, error) {
+func queryPluginRegistry(pluginCfg PluginConfig, platform Platform) (*registryResponse, error) {
 	params := url.Values{
 		"version": {pluginCfg.Version},
-		"os":      {runtime.GOOS},
-		"arch":    {runtime.GOARCH},
+		"os":      {platform.OS},
+		"arch":    {platform.Arch},
+	}
+
+	endpoint := registryEndpoint
+	if pluginCfg.Registry != "" {
+		endpoint = pluginCfg.Registry
 	}
 
-	u, err := url.Parse(registryEndpoint)
+	u, err := url.Parse(endpoint)
 	if err != nil {
 		return nil, fmt.Errorf("internal error: %w", err)
 	}
 
-	u.Path = fmt.Sprintf("/v1/plugins/%s", strings.ToLower(pluginCfg.Source))
+	u.Path = path.Join("/", u.Path, "v1/plugins", strings.ToLower(pluginCfg.Source))
 	u.RawQuery = params.Encode()
 
 	client := retryablehttp.NewClient()
@@ -44,7 +44,27 @@ func TestQueryPluginRegistry(t *testing.T) {
 	registryEndpoint = ts.URL
 
 	// Call the function being tested
-	res, err := queryPluginRegistry(pluginCfg)
+	res, err := queryPluginRegistry(pluginCfg, CurrentPlatform())
 	require.NoError(t, err)
 	assert.Equal(t, expectedResponse, res)
 }
+
+func TestQueryPluginRegistryOverride(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/registry/v1/plugins/mach-composer/example", r.URL.Path)
+
+		err := json.NewEncoder(w).Encode(&registryResponse{URL: "https://example.org/download.zip"})
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	pluginCfg := PluginConfig{
+		Version:  "1.0.0",
+		Source:   "mach-composer/example",
+		Registry: ts.URL + "/registry",
+	}
+
+	res, err := queryPluginRegistry(pluginCfg, CurrentPlatform())
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.org/download.zip", res.URL)
+}
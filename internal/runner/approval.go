@@ -0,0 +1,238 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+)
+
+// ApprovalTokensEnv contains a comma separated list of approval tokens of changes that are approved up front
+const ApprovalTokensEnv = "MC_APPROVAL_TOKENS"
+
+// Approver decides whether the planned changes of a node may be applied
+type Approver interface {
+	Approve(ctx context.Context, n graph.Node, summary *terraform.PlanSummary) (bool, error)
+}
+
+// planToApply returns the file with the plan that is applied to a node, relative to its path. A plan stored by
+// `mach-composer plan` is applied when there is one, unless the node is destroyed. Otherwise, when the changes must be
+// checked before they are applied, the node is planned to a file of its own so runs never share a plan. An empty
+// filename means terraform apply plans the changes itself.
+func (gr *GraphRunner) planToApply(ctx context.Context, n graph.Node, opts *ApplyOptions, check bool) (string, error) {
+	if terraform.HasPlan(n.Path()) {
+		if !opts.Destroy {
+			return terraform.PlanFile, nil
+		}
+		log.Ctx(ctx).Warn().Msgf("Ignoring the stored plan of %s, as it cannot be used to destroy it", n.Identifier())
+	}
+	if !check {
+		return "", nil
+	}
+
+	f, err := os.CreateTemp(n.Path(), "apply-*.plan")
+	if err != nil {
+		return "", fmt.Errorf("failed to create plan file for %s: %w", n.Identifier(), err)
+	}
+	_ = f.Close()
+	filename := filepath.Base(f.Name())
+
+	log.Ctx(ctx).Info().Msgf("Running terraform plan for %s", n.Path())
+	emitPhase(ctx, PhasePlan)
+
+	var pOpts []terraform.PlanOption
+	if opts.Destroy {
+		pOpts = append(pOpts, terraform.PlanWithDestroy())
+	}
+	out, err := gr.withRetries(ctx, PhasePlan, func() (string, error) {
+		return terraform.PlanTo(ctx, n.Path(), filename, pOpts...)
+	})
+	logCommandOutput(ctx, out)
+	if err != nil {
+		_ = terraform.RemovePlan(n.Path(), filename)
+		return "", fmt.Errorf("failed to plan %s: %w", n.Identifier(), err)
+	}
+	return filename, nil
+}
+
+// removePlans removes the applied plan, and the plan stored by `mach-composer plan` which is outdated once the node is
+// applied
+func removePlans(ctx context.Context, n graph.Node, filename string) {
+	for _, f := range []string{filename, terraform.PlanFile} {
+		if f == "" {
+			continue
+		}
+		if err := terraform.RemovePlan(n.Path(), f); err != nil {
+			log.Ctx(ctx).Warn().Err(err).Msgf("Failed to remove plan %s of %s", f, n.Identifier())
+		}
+	}
+}
+
+// approveChanges asks the approver to approve the planned changes of a node
+func approveChanges(ctx context.Context, n graph.Node, summary *terraform.PlanSummary, opts *ApplyOptions) (bool, error) {
+	if !summary.HasChanges() {
+		log.Ctx(ctx).Info().Msgf("No changes to approve for %s", n.Identifier())
+		return true, nil
+	}
+	if opts.Approver == nil {
+		return false, fmt.Errorf("changes to %s require approval, but they cannot be approved", n.Identifier())
+	}
+
+	emitPhase(ctx, PhaseApproval)
+	return opts.Approver.Approve(ctx, n, summary)
+}
+
+// needsApproval returns whether the planned changes of a node must be approved before they are applied. Besides the
+// changes of sites that require approval, this includes plans made by the apply itself when auto approve is not set,
+// as terraform applies a plan without asking for confirmation.
+func needsApproval(n graph.Node, opts *ApplyOptions, planned bool) bool {
+	switch approvalFor(n) {
+	case config.ApprovalRequired:
+		return true
+	case config.ApprovalAuto:
+		return false
+	}
+	return planned && !opts.AutoApprove
+}
+
+// approvalFor returns the approval setting of the site the node belongs to
+func approvalFor(n graph.Node) config.ApprovalType {
+	switch node := n.(type) {
+	case *graph.Site:
+		return node.SiteConfig.Approval
+	case *graph.SiteComponent:
+		return node.SiteConfig.Approval
+	case *graph.Removed:
+		return node.Approval
+	}
+	return ""
+}
+
+// ApprovalToken returns a token that identifies the planned changes of a node. It is derived from the complete plan,
+// so planning the same changes to the same state again results in the same token, and changes can be approved up
+// front.
+func ApprovalToken(n graph.Node, summary *terraform.PlanSummary) string {
+	h := sha256.Sum256([]byte(n.Identifier() + "\n" + summary.Digest))
+	return hex.EncodeToString(h[:])[:12]
+}
+
+// TerminalApprover asks for confirmation on the terminal. Questions of nodes that run in parallel are asked one by one
+type TerminalApprover struct {
+	in  *bufio.Reader
+	out io.Writer
+	mu  sync.Mutex
+}
+
+func NewTerminalApprover() *TerminalApprover {
+	return &TerminalApprover{
+		in:  bufio.NewReader(os.Stdin),
+		out: os.Stdout,
+	}
+}
+
+func (a *TerminalApprover) Approve(_ context.Context, n graph.Node, summary *terraform.PlanSummary) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, _ = fmt.Fprintf(a.out, "\nChanges to %s require approval.\n%s\n\n", n.Identifier(), summary)
+	_, _ = fmt.Fprintf(a.out, "Do you want to apply these changes? Only 'yes' will be accepted: ")
+
+	answer, err := a.in.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	return strings.TrimSpace(answer) == "yes", nil
+}
+
+// TokenApprover approves changes without a terminal, for example in CI. Changes are approved when their approval token
+// is listed in the MC_APPROVAL_TOKENS environment variable, or added as a line to the approval file. If an approval file
+// is set the file is checked until the timeout expires.
+type TokenApprover struct {
+	File     string
+	Timeout  time.Duration
+	Interval time.Duration
+}
+
+func (a *TokenApprover) Approve(ctx context.Context, n graph.Node, summary *terraform.PlanSummary) (bool, error) {
+	token := ApprovalToken(n, summary)
+
+	for _, t := range strings.Split(os.Getenv(ApprovalTokensEnv), ",") {
+		if strings.TrimSpace(t) == token {
+			log.Ctx(ctx).Info().Msgf("Changes to %s are approved with token %s", n.Identifier(), token)
+			return true, nil
+		}
+	}
+
+	// These messages are logged directly instead of with the node logger, as that might hold them back until the
+	// batch completes
+	if a.File == "" {
+		log.Error().Msgf(
+			"Changes to %s require approval. To approve them add %s to the %s environment variable.\n%s",
+			n.Identifier(), token, ApprovalTokensEnv, summary,
+		)
+		return false, nil
+	}
+
+	log.Warn().Msgf(
+		"Changes to %s require approval. Waiting for approval token %s to be added to %s.\n%s",
+		n.Identifier(), token, a.File, summary,
+	)
+
+	interval := a.Interval
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(a.Timeout)
+
+	for {
+		approved, err := a.fileContains(token)
+		if err != nil {
+			return false, err
+		}
+		if approved {
+			log.Ctx(ctx).Info().Msgf("Changes to %s are approved with token %s", n.Identifier(), token)
+			return true, nil
+		}
+		if time.Now().After(deadline) {
+			return false, fmt.Errorf("timed out waiting for approval of %s", n.Identifier())
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (a *TokenApprover) fileContains(token string) (bool, error) {
+	data, err := utils.AFS.ReadFile(a.File)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read approval file: %w", err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == token {
+			return true, nil
+		}
+	}
+	return false, nil
+}
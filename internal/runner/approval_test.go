@@ -0,0 +1,142 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	internalgraph "github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+)
+
+func TestApprovalFor(t *testing.T) {
+	site := &internalgraph.Site{SiteConfig: config.SiteConfig{Approval: config.ApprovalRequired}}
+	component := &internalgraph.SiteComponent{SiteConfig: config.SiteConfig{Approval: config.ApprovalAuto}}
+
+	assert.Equal(t, config.ApprovalRequired, approvalFor(site))
+	assert.Equal(t, config.ApprovalAuto, approvalFor(component))
+	assert.Equal(t, config.ApprovalType(""), approvalFor(&internalgraph.Project{}))
+}
+
+func TestNeedsApproval(t *testing.T) {
+	required := &internalgraph.Site{SiteConfig: config.SiteConfig{Approval: config.ApprovalRequired}}
+	auto := &internalgraph.Site{SiteConfig: config.SiteConfig{Approval: config.ApprovalAuto}}
+	other := &internalgraph.Site{}
+
+	assert.True(t, needsApproval(required, &ApplyOptions{AutoApprove: true}, false))
+	assert.False(t, needsApproval(auto, &ApplyOptions{}, true))
+	// Plans made by the apply itself are confirmed like terraform would, while stored plans are applied as-is
+	assert.True(t, needsApproval(other, &ApplyOptions{}, true))
+	assert.False(t, needsApproval(other, &ApplyOptions{}, false))
+	assert.False(t, needsApproval(other, &ApplyOptions{AutoApprove: true}, true))
+}
+
+func TestTokenApproverEnvironment(t *testing.T) {
+	n := new(internalgraph.NodeMock)
+	n.On("Identifier").Return("my-site")
+	summary := &terraform.PlanSummary{Changes: []terraform.ResourceChange{
+		{Address: "aws_s3_bucket.this", Action: terraform.ActionDelete},
+	}, Digest: "a"}
+
+	approver := &TokenApprover{}
+
+	approved, err := approver.Approve(context.Background(), n, summary)
+	require.NoError(t, err)
+	assert.False(t, approved)
+
+	t.Setenv(ApprovalTokensEnv, "other, "+ApprovalToken(n, summary))
+	approved, err = approver.Approve(context.Background(), n, summary)
+	require.NoError(t, err)
+	assert.True(t, approved)
+
+	// A different plan needs a new approval
+	summary.Changes[0].Action = terraform.ActionUpdate
+	summary.Digest = "b"
+	approved, err = approver.Approve(context.Background(), n, summary)
+	require.NoError(t, err)
+	assert.False(t, approved)
+}
+
+func TestTokenApproverFile(t *testing.T) {
+	afs := utils.AFS
+	utils.AFS = &afero.Afero{Fs: afero.NewMemMapFs()}
+	defer func() { utils.AFS = afs }()
+
+	n := new(internalgraph.NodeMock)
+	n.On("Identifier").Return("my-site")
+	summary := &terraform.PlanSummary{Changes: []terraform.ResourceChange{
+		{Address: "aws_s3_bucket.this", Action: terraform.ActionCreate},
+	}, Digest: "a"}
+
+	filename := filepath.Join("approvals", "tokens.txt")
+	approver := &TokenApprover{File: filename, Timeout: time.Second, Interval: 10 * time.Millisecond}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = utils.AFS.WriteFile(filename, []byte("other\n"+ApprovalToken(n, summary)+"\n"), 0644)
+	}()
+
+	approved, err := approver.Approve(context.Background(), n, summary)
+	require.NoError(t, err)
+	assert.True(t, approved)
+
+	approver.Timeout = 50 * time.Millisecond
+	summary.Changes[0].Address = "aws_s3_bucket.other"
+	summary.Digest = "b"
+	_, err = approver.Approve(context.Background(), n, summary)
+	assert.ErrorContains(t, err, "timed out waiting for approval of my-site")
+}
+
+func TestPlanToApply(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as terraform binary")
+	}
+
+	tmp := t.TempDir()
+	calls := filepath.Join(tmp, "calls")
+	binary := filepath.Join(tmp, "terraform")
+	require.NoError(t, os.WriteFile(binary, []byte(`#!/bin/sh
+echo "$@" >> `+calls+`
+`), 0755))
+	ctx := utils.ContextWithTerraformBinary(context.Background(), binary)
+
+	n := internalgraph.NewRemoved(filepath.Join(tmp, "site-1"), "site-1", internalgraph.SiteType)
+	require.NoError(t, os.MkdirAll(n.Path(), 0700))
+	runner := NewGraphRunner(nil, nil, 1)
+
+	// Nodes whose changes are not checked are planned by terraform apply itself
+	filename, err := runner.planToApply(ctx, n, &ApplyOptions{}, false)
+	require.NoError(t, err)
+	assert.Empty(t, filename)
+
+	// A stored plan is applied as-is
+	require.NoError(t, os.WriteFile(filepath.Join(n.Path(), terraform.PlanFile), nil, 0600))
+	filename, err = runner.planToApply(ctx, n, &ApplyOptions{}, true)
+	require.NoError(t, err)
+	assert.Equal(t, terraform.PlanFile, filename)
+	assert.NoFileExists(t, calls)
+
+	// A stored plan cannot be used to destroy the node, so it is planned to a file of its own
+	filename, err = runner.planToApply(ctx, n, &ApplyOptions{Destroy: true}, true)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(filename, "apply-"))
+	assert.FileExists(t, filepath.Join(n.Path(), filename))
+
+	data, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	assert.Equal(t, "plan -destroy -out="+filename+"\n", string(data))
+
+	removePlans(ctx, n, filename)
+	assert.NoFileExists(t, filepath.Join(n.Path(), filename))
+	assert.NoFileExists(t, filepath.Join(n.Path(), terraform.PlanFile))
+}
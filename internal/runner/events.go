@@ -0,0 +1,169 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	// EventNodeQueued is emitted for every node in the graph before the first batch starts
+	EventNodeQueued EventType = "node_queued"
+	// EventBatchStarted is emitted when all nodes of a batch can start, as their parents are done
+	EventBatchStarted  EventType = "batch_started"
+	EventBatchFinished EventType = "batch_finished"
+	EventNodeStarted   EventType = "node_started"
+	// EventNodeSkipped is emitted when a node is not run, or when it stops early. The reason explains why
+	EventNodeSkipped EventType = "node_skipped"
+	// EventPhaseChanged is emitted when a node starts running a different terraform command
+	EventPhaseChanged EventType = "phase_changed"
+	// EventNodeOutput is emitted for every line of terraform output of a node
+	EventNodeOutput EventType = "node_output"
+	// EventNodeRetried is emitted when a terraform command failed with a transient error and is run again
+	EventNodeRetried  EventType = "node_retried"
+	EventNodeFinished EventType = "node_finished"
+)
+
+type Phase string
+
+const (
+	PhaseInit     Phase = "init"
+	PhaseValidate Phase = "validate"
+	PhasePlan     Phase = "plan"
+	PhaseApply    Phase = "apply"
+	// PhaseApproval is the phase in which planned changes wait to be approved
+	PhaseApproval Phase = "approval"
+	PhaseShow     Phase = "show"
+	PhaseProxy    Phase = "proxy"
+	// PhaseProvidersLock is the phase in which the checksums of the providers are added to the lock file
+	PhaseProvidersLock Phase = "providers_lock"
+)
+
+type Result string
+
+const (
+	ResultSucceeded Result = "succeeded"
+	ResultFailed    Result = "failed"
+	ResultSkipped   Result = "skipped"
+)
+
+// Event describes a change in the progress of a graph run. Only the fields relevant for the event type are set. The
+// json representation is used by the event sinks and should be kept backwards compatible.
+type Event struct {
+	Type    EventType `json:"type"`
+	Time    time.Time `json:"time"`
+	Batch   int       `json:"batch"`
+	Nodes   int       `json:"nodes,omitempty"`
+	Node    string    `json:"node,omitempty"`
+	Phase   Phase     `json:"phase,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+	Attempt int       `json:"attempt,omitempty"`
+	Retries int       `json:"retries,omitempty"`
+	Result  Result    `json:"result,omitempty"`
+	Line    string    `json:"line,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	Err     error     `json:"-"`
+}
+
+// EventSubscriber receives the events emitted by the GraphRunner. Events are delivered one at a time and in order
+type EventSubscriber interface {
+	HandleEvent(event Event)
+}
+
+type eventBus struct {
+	subscribers []EventSubscriber
+	mu          sync.Mutex
+}
+
+func (b *eventBus) subscribe(s EventSubscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, s)
+}
+
+func (b *eventBus) unsubscribe(s EventSubscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range b.subscribers {
+		if b.subscribers[i] == s {
+			b.subscribers = append(b.subscribers[:i], b.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (b *eventBus) emit(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	event.Time = time.Now()
+	if event.Err != nil {
+		event.Error = event.Err.Error()
+	}
+	for _, s := range b.subscribers {
+		s.HandleEvent(event)
+	}
+}
+
+const nodeEventsKey = "node-events"
+
+// nodeEvents emits events on behalf of the node that is currently running, so executor functions can report their
+// progress
+type nodeEvents struct {
+	bus     *eventBus
+	batch   int
+	node    string
+	skipped bool
+	retries int
+}
+
+func (e *nodeEvents) emit(event Event) {
+	event.Batch = e.batch
+	event.Node = e.node
+	switch event.Type {
+	case EventNodeRetried:
+		e.retries++
+	case EventNodeFinished:
+		event.Retries = e.retries
+	}
+	e.bus.emit(event)
+}
+
+func (e *nodeEvents) result(err error) Result {
+	switch {
+	case err != nil:
+		return ResultFailed
+	case e.skipped:
+		return ResultSkipped
+	default:
+		return ResultSucceeded
+	}
+}
+
+func contextWithNodeEvents(ctx context.Context, e *nodeEvents) context.Context {
+	return context.WithValue(ctx, nodeEventsKey, e)
+}
+
+func nodeEventsFromContext(ctx context.Context) *nodeEvents {
+	if v := ctx.Value(nodeEventsKey); v != nil {
+		return v.(*nodeEvents)
+	}
+	return nil
+}
+
+// emitPhase reports that the current node starts running the given terraform command
+func emitPhase(ctx context.Context, phase Phase) {
+	if e := nodeEventsFromContext(ctx); e != nil {
+		e.emit(Event{Type: EventPhaseChanged, Phase: phase})
+	}
+}
+
+// emitSkipped reports that the current node stops without running terraform for the given reason
+func emitSkipped(ctx context.Context, reason string) {
+	if e := nodeEventsFromContext(ctx); e != nil {
+		e.skipped = true
+		e.emit(Event{Type: EventNodeSkipped, Reason: reason})
+	}
+}
@@ -5,14 +5,17 @@ import (
 	"fmt"
 	"github.com/mach-composer/mach-composer-cli/internal/batcher"
 	"github.com/mach-composer/mach-composer-cli/internal/cli"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
 	"github.com/mach-composer/mach-composer-cli/internal/hash"
+	"github.com/mach-composer/mach-composer-cli/internal/snapshot"
 	"github.com/mach-composer/mach-composer-cli/internal/terraform"
 	"github.com/mach-composer/mach-composer-cli/internal/utils"
-	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/exp/maps"
 	"golang.org/x/sync/semaphore"
+	"io"
+	"os"
 	"sort"
 	"sync"
 	"time"
@@ -29,6 +32,15 @@ type GraphRunner struct {
 	workers int
 	batch   batcher.BatchFunc
 	hash    hash.Handler
+	events  eventBus
+	// providers is the provider cache that is shared between nodes, if any
+	providers *ProviderCache
+	// lockDir is the directory in which the lock files of the nodes are kept, if any
+	lockDir string
+	// retries decides which failed terraform commands are run again, if any
+	retries *RetryPolicy
+	// snapshots keeps the generated terraform of applied nodes, so they can be destroyed once removed, if set
+	snapshots *snapshot.Store
 }
 
 func NewGraphRunner(batcher batcher.BatchFunc, hashHandler hash.Handler, workers int) *GraphRunner {
@@ -39,41 +51,123 @@ func NewGraphRunner(batcher batcher.BatchFunc, hashHandler hash.Handler, workers
 	}
 }
 
+// Subscribe registers a subscriber that receives the events of every run
+func (gr *GraphRunner) Subscribe(s EventSubscriber) {
+	gr.events.subscribe(s)
+}
+
+// logProgress writes the progress of a run to the logger of ctx, which differs between runs when the terminal UI is
+// shown, until the returned function is called
+func (gr *GraphRunner) logProgress(ctx context.Context) func() {
+	s := NewLogSubscriber(ctx)
+	gr.events.subscribe(s)
+	return func() { gr.events.unsubscribe(s) }
+}
+
+// UseProviderCache shares downloaded terraform providers between nodes through the given cache
+func (gr *GraphRunner) UseProviderCache(c *ProviderCache) {
+	gr.providers = c
+}
+
+// UseLockDir keeps the dependency lock files of all nodes in the given directory. They are copied to the node before
+// terraform is initialized, and copied back when terraform changes them.
+func (gr *GraphRunner) UseLockDir(dir string) {
+	gr.lockDir = dir
+}
+
+// UseRetryPolicy runs terraform init, plan and apply again when they fail with an error matching the policy. Applies
+// of a saved plan are not retried, as the plan is stale once part of it is applied.
+func (gr *GraphRunner) UseRetryPolicy(p *RetryPolicy) {
+	gr.retries = p
+}
+
+// UseSnapshotStore keeps the generated terraform of every applied node in the given store, so the resources of nodes
+// that are removed from the configuration can be destroyed later on
+func (gr *GraphRunner) UseSnapshotStore(s *snapshot.Store) {
+	gr.snapshots = s
+}
+
+// needsInit returns whether terraform should be initialized for the node before running other commands
+func (gr *GraphRunner) needsInit(ctx context.Context, n graph.Node) bool {
+	return !terraformIsInitialized(ctx, n.Path()) || gr.lockFileChanged(n)
+}
+
+func (gr *GraphRunner) terraformInit(ctx context.Context, n graph.Node, opts ...terraform.InitOption) (string, error) {
+	if err := gr.restoreLockFile(n); err != nil {
+		return "", err
+	}
+
+	out, err := gr.withRetries(ctx, PhaseInit, func() (string, error) {
+		if gr.providers != nil {
+			return gr.providers.init(ctx, n, opts...)
+		}
+		return terraform.Init(ctx, n.Path(), opts...)
+	})
+	if err != nil {
+		return out, err
+	}
+
+	return out, gr.storeLockFile(n)
+}
+
 func (gr *GraphRunner) run(ctx context.Context, g *graph.Graph, f executorFunc, ignoreChangeDetection bool) error {
+	defer gr.logProgress(ctx)()
+
 	if err := taintGraph(ctx, g, gr.hash); err != nil {
 		return err
 	}
 
+	if gr.providers != nil {
+		defer func() {
+			if summary := gr.providers.Summary(); summary != "" {
+				log.Info().Msg(summary)
+			}
+		}()
+	}
+
 	batches := gr.batch(g)
 
 	keys := maps.Keys(batches)
 	sort.Ints(keys)
 	for i, k := range keys[1:] {
-		log.Info().Msgf("Running batch %d with %d nodes", i, len(batches[k]))
+		for _, n := range batches[k] {
+			gr.events.emit(Event{Type: EventNodeQueued, Batch: i, Node: n.Identifier()})
+		}
+	}
+
+	for i, k := range keys[1:] {
+		gr.events.emit(Event{Type: EventBatchStarted, Batch: i, Nodes: len(batches[k])})
 
 		errChan := make(chan error, len(batches[k]))
+		grouped := cli.GroupedOutputFromContext(ctx)
 		var outputs []*cli.BufferedWriter
 
 		wg := &sync.WaitGroup{}
 		sem := semaphore.NewWeighted(int64(gr.workers))
 
-		// Start a ticker to show we are still running
+		// When output is grouped nothing is shown until the batch completes, so show we are still running
 		ticker := make(chan struct{})
-		go func() {
-			for {
-				select {
-				case <-ticker:
-					return
-				default:
-					log.Info().Msgf("Waiting for batch %d to complete", i)
-					time.Sleep(1 * time.Second)
+		if grouped {
+			go func() {
+				for {
+					select {
+					case <-ticker:
+						return
+					default:
+						log.Info().Msgf("Waiting for batch %d to complete", i)
+						time.Sleep(1 * time.Second)
+					}
 				}
-			}
-		}()
+			}()
+		}
+
+		// Writes from nodes running in parallel should not be interleaved halfway through a log line
+		w := &syncWriter{Writer: cli.LogWriterFromContext(ctx)}
 
 		for _, n := range batches[k] {
 			if n.Tainted() == false && ignoreChangeDetection == false {
-				log.Info().Msgf("Skipping %s because it has no changes", n.Identifier())
+				gr.events.emit(Event{Type: EventNodeSkipped, Batch: i, Node: n.Identifier(), Reason: ReasonNoChanges})
+				gr.events.emit(Event{Type: EventNodeFinished, Batch: i, Node: n.Identifier(), Result: ResultSkipped})
 				continue
 			}
 
@@ -82,33 +176,21 @@ func (gr *GraphRunner) run(ctx context.Context, g *graph.Graph, f executorFunc,
 			}
 			wg.Add(1)
 
-			go func(ctx context.Context, n graph.Node) {
-				defer wg.Done()
-				defer sem.Release(1)
-
-				w := cli.LogWriterFromContext(ctx)
+			var nw io.Writer = w
+			if grouped {
 				bw := cli.NewBufferedWriter(w)
-				l := log.Output(bw).With().Str("identifier", n.Identifier()).Logger()
-				ctx = l.WithContext(ctx)
-
 				outputs = append(outputs, bw)
+				nw = bw
+			}
 
-				defer func() {
-					if cli.GithubCIFromContext(ctx) {
-						log.Ctx(ctx).Info().Msg("::endgroup::")
-					}
-				}()
-
-				if cli.GithubCIFromContext(ctx) {
-					log.Ctx(ctx).Info().Msgf("::group::{%s}", n.Identifier())
-				}
+			go func(ctx context.Context, i int, n graph.Node) {
+				defer wg.Done()
+				defer sem.Release(1)
 
-				err := f(ctx, n)
-				if err != nil {
+				if err := gr.runNode(ctx, i, n, nw, f); err != nil {
 					errChan <- err
-					return
 				}
-			}(ctx, n)
+			}(ctx, i, n)
 		}
 		wg.Wait()
 		close(ticker)
@@ -126,10 +208,12 @@ func (gr *GraphRunner) run(ctx context.Context, g *graph.Graph, f executorFunc,
 				errors = append(errors, err)
 			}
 
-			return cli.NewGroupedError(fmt.Sprintf("batch run %d failed (%d errors)", i, len(errors)), errors)
+			err := cli.NewGroupedError(fmt.Sprintf("batch run %d failed (%d errors)", i, len(errors)), errors)
+			gr.events.emit(Event{Type: EventBatchFinished, Batch: i, Result: ResultFailed, Err: err})
+			return err
 		}
 
-		log.Info().Msgf("Finished batch %d", i)
+		gr.events.emit(Event{Type: EventBatchFinished, Batch: i, Result: ResultSucceeded})
 	}
 
 	log.Info().Msgf("Finished all batches")
@@ -137,12 +221,49 @@ func (gr *GraphRunner) run(ctx context.Context, g *graph.Graph, f executorFunc,
 	return nil
 }
 
+// runNode runs the executor function on the node, writing its output to w and emitting its progress as events
+func (gr *GraphRunner) runNode(ctx context.Context, i int, n graph.Node, w io.Writer, f executorFunc) error {
+	events := &nodeEvents{bus: &gr.events, batch: i, node: n.Identifier()}
+	events.emit(Event{Type: EventNodeStarted})
+	ctx = contextWithNodeEvents(ctx, events)
+
+	l := log.Output(w).With().Str("identifier", n.Identifier()).Logger()
+	ctx = l.WithContext(ctx)
+
+	var lw *cli.LineWriter
+	if !cli.GroupedOutputFromContext(ctx) {
+		lw = cli.NewLineWriter(func(line string) {
+			events.emit(Event{Type: EventNodeOutput, Line: line})
+			logTerraformLine(ctx, n, line)
+		})
+		ctx = utils.ContextWithOutputWriter(ctx, lw)
+	}
+
+	defer func() {
+		if cli.GithubCIFromContext(ctx) {
+			log.Ctx(ctx).Info().Msg("::endgroup::")
+		}
+	}()
+
+	if cli.GithubCIFromContext(ctx) {
+		log.Ctx(ctx).Info().Msgf("::group::{%s}", n.Identifier())
+	}
+
+	err := f(ctx, n)
+	if lw != nil {
+		_ = lw.Flush()
+	}
+	events.emit(Event{Type: EventNodeFinished, Result: events.result(err), Err: err})
+	return err
+}
+
 func (gr *GraphRunner) TerraformApply(ctx context.Context, dg *graph.Graph, opts *ApplyOptions) error {
 	if err := gr.run(ctx, dg, func(ctx context.Context, n graph.Node) error {
-		if !terraformIsInitialized(ctx, n.Path()) || opts.ForceInit {
+		if gr.needsInit(ctx, n) || opts.ForceInit {
 			log.Ctx(ctx).Info().Msgf("Running terraform init for %s", n.Path())
-			out, err := terraform.Init(ctx, n.Path())
-			log.Ctx(ctx).Info().Msg(out)
+			emitPhase(ctx, PhaseInit)
+			out, err := gr.terraformInit(ctx, n)
+			logCommandOutput(ctx, out)
 			if err != nil {
 				return err
 			}
@@ -150,47 +271,173 @@ func (gr *GraphRunner) TerraformApply(ctx context.Context, dg *graph.Graph, opts
 			log.Ctx(ctx).Info().Msgf("Skipping terraform init for %s", n.Path())
 		}
 
-		var aOpts []terraform.ApplyOption
-		if opts.Destroy {
-			aOpts = append(aOpts, terraform.ApplyWithDestroy())
+		out, err := gr.applyChanges(ctx, n, opts, approvalFor(n) == config.ApprovalRequired || hasPolicies(n))
+		if err != nil {
+			err = fmt.Errorf("failed to apply %s: %w", n.Identifier(), err)
 		}
-		if opts.AutoApprove {
-			aOpts = append(aOpts, terraform.ApplyWithAutoApprove())
+
+		if logErr := logTerraformOutput(ctx, out); logErr != nil {
+			return logErr
 		}
-		if cli.OutputFromContext(ctx) == cli.OutputTypeJSON {
-			aOpts = append(aOpts, terraform.ApplyWithJson())
+
+		if err != nil {
+			// Without a new hash the node is applied again on the next run, like when its changes were not approved
+			return err
+		}
+
+		log.Ctx(ctx).Debug().Msgf("Storing new hash for %s", n.Path())
+		if err := gr.hash.Store(ctx, n); err != nil {
+			log.Ctx(ctx).Warn().Err(err).Msgf("Failed to store hash for %s", n.Identifier())
 		}
+		gr.updateSnapshot(ctx, n, opts.Destroy)
+		return nil
+
+	}, opts.IgnoreChangeDetection); err != nil {
+		return err
+	}
+
+	return nil
+}
 
-		out, err := terraform.Apply(ctx, n.Path(), aOpts...)
+// applyChanges applies the changes of a node. When check is set the changes are planned first, so they can be checked
+// against the policies and approved before that exact plan is applied.
+func (gr *GraphRunner) applyChanges(ctx context.Context, n graph.Node, opts *ApplyOptions, check bool) (string, error) {
+	planFile, err := gr.planToApply(ctx, n, opts, check)
+	if err != nil {
+		return "", err
+	}
+	// A plan can only be applied once, so it is removed whether applying it succeeds or not
+	defer removePlans(ctx, n, planFile)
+
+	if planFile != "" {
+		summary, err := terraform.ShowPlanSummary(ctx, n.Path(), planFile)
 		if err != nil {
-			err = fmt.Errorf("failed to apply %s: %w", n.Identifier(), err)
+			return "", fmt.Errorf("failed to summarize plan of %s: %w", n.Identifier(), err)
+		}
+		if err := checkPolicies(n, summary); err != nil {
+			return "", err
 		}
 
-		if cli.OutputFromContext(ctx) == cli.OutputTypeJSON {
-			logLines, err := cli.ParseTerraformJsonOutput(out)
+		if needsApproval(n, opts, planFile != terraform.PlanFile) {
+			approved, err := approveChanges(ctx, n, summary, opts)
 			if err != nil {
-				return err
+				return "", err
 			}
-			for _, logLine := range logLines {
-				level, err := zerolog.ParseLevel(logLine.Level)
-				if err != nil {
-					level = zerolog.InfoLevel
-				}
-				log.Ctx(ctx).WithLevel(level).Fields(logLine.Remainder).Msg(logLine.Message)
+			if !approved {
+				return "", fmt.Errorf("changes to %s were not approved", n.Identifier())
 			}
-		} else {
-			log.Ctx(ctx).Info().Msg(out)
 		}
+	}
 
-		log.Ctx(ctx).Debug().Msgf("Storing new hash for %s", n.Path())
-		if err := gr.hash.Store(ctx, n); err != nil {
-			log.Ctx(ctx).Warn().Err(err).Msgf("Failed to store hash for %s", n.Identifier())
+	var aOpts []terraform.ApplyOption
+	if cli.OutputFromContext(ctx) == cli.OutputTypeJSON {
+		aOpts = append(aOpts, terraform.ApplyWithJson())
+	}
+
+	emitPhase(ctx, PhaseApply)
+	if planFile != "" {
+		// A plan is stale once part of it is applied, so applying it again cannot succeed
+		return terraform.ApplyPlan(ctx, n.Path(), planFile, aOpts...)
+	}
+
+	if opts.Destroy {
+		aOpts = append(aOpts, terraform.ApplyWithDestroy())
+	}
+	if opts.AutoApprove || approvalFor(n) == config.ApprovalAuto {
+		aOpts = append(aOpts, terraform.ApplyWithAutoApprove())
+	}
+	return gr.withRetries(ctx, PhaseApply, func() (string, error) {
+		return terraform.Apply(ctx, n.Path(), aOpts...)
+	})
+}
+
+// updateSnapshot stores the generated terraform of an applied node, or removes it when the node is destroyed
+func (gr *GraphRunner) updateSnapshot(ctx context.Context, n graph.Node, destroyed bool) {
+	if gr.snapshots == nil || n.Type() == graph.ProjectType {
+		return
+	}
+
+	if destroyed {
+		if err := gr.snapshots.Delete(n.Identifier()); err != nil {
+			log.Ctx(ctx).Warn().Err(err).Msgf("Failed to remove snapshot of %s", n.Identifier())
 		}
+		return
+	}
 
-		return err
+	if err := gr.snapshots.Save(n); err != nil {
+		log.Ctx(ctx).Warn().Err(err).Msgf("Failed to store snapshot of %s", n.Identifier())
+	}
+}
 
-	}, opts.IgnoreChangeDetection); err != nil {
-		return err
+// TerraformDestroyRemoved destroys the resources of nodes that are not part of the configuration anymore. The last
+// applied terraform of every node is restored from its snapshot, and the nodes are destroyed one by one in the given
+// order. The snapshot and directory of a node are removed once it is destroyed.
+func (gr *GraphRunner) TerraformDestroyRemoved(ctx context.Context, cfg *config.MachConfig, removed []*snapshot.Snapshot, opts *ApplyOptions) error {
+	if gr.snapshots == nil {
+		return fmt.Errorf("no snapshot store configured")
+	}
+	defer gr.logProgress(ctx)()
+
+	destroyOpts := *opts
+	destroyOpts.Destroy = true
+
+	for i, s := range removed {
+		gr.events.emit(Event{Type: EventNodeQueued, Batch: i, Node: s.Identifier})
+	}
+
+	w := cli.LogWriterFromContext(ctx)
+	for i, s := range removed {
+		gr.events.emit(Event{Type: EventBatchStarted, Batch: i, Nodes: 1})
+
+		var nw io.Writer = w
+		var bw *cli.BufferedWriter
+		if cli.GroupedOutputFromContext(ctx) {
+			bw = cli.NewBufferedWriter(w)
+			nw = bw
+		}
+
+		err := gr.runNode(ctx, i, s.Node(cfg), nw, func(ctx context.Context, n graph.Node) error {
+			log.Ctx(ctx).Info().Msgf("Restoring last applied terraform of %s in %s", n.Identifier(), n.Path())
+			if err := s.Restore(); err != nil {
+				return err
+			}
+
+			log.Ctx(ctx).Info().Msgf("Running terraform init for %s", n.Path())
+			emitPhase(ctx, PhaseInit)
+			out, err := gr.terraformInit(ctx, n)
+			logCommandOutput(ctx, out)
+			if err != nil {
+				return err
+			}
+
+			// Destroying is always planned first, so the changes are checked against the policies and approved like
+			// any other change
+			out, err = gr.applyChanges(ctx, n, &destroyOpts, true)
+			if logErr := logTerraformOutput(ctx, out); logErr != nil {
+				return logErr
+			}
+			if err != nil {
+				return fmt.Errorf("failed to destroy %s: %w", n.Identifier(), err)
+			}
+
+			if err := gr.snapshots.Delete(n.Identifier()); err != nil {
+				return fmt.Errorf("failed to remove snapshot of %s: %w", n.Identifier(), err)
+			}
+			log.Ctx(ctx).Info().Msgf("Removing %s, as %s is destroyed", n.Path(), n.Identifier())
+			return os.RemoveAll(n.Path())
+		})
+
+		if bw != nil {
+			if flushErr := bw.Flush(); flushErr != nil {
+				return flushErr
+			}
+		}
+
+		if err != nil {
+			gr.events.emit(Event{Type: EventBatchFinished, Batch: i, Result: ResultFailed, Err: err})
+			return err
+		}
+		gr.events.emit(Event{Type: EventBatchFinished, Batch: i, Result: ResultSucceeded})
 	}
 
 	return nil
@@ -199,13 +446,15 @@ func (gr *GraphRunner) TerraformApply(ctx context.Context, dg *graph.Graph, opts
 func (gr *GraphRunner) TerraformValidate(ctx context.Context, dg *graph.Graph) error {
 	return gr.run(ctx, dg, func(ctx context.Context, n graph.Node) error {
 		log.Ctx(ctx).Info().Msgf("Running terraform init without backend for %s", n.Path())
-		out, err := terraform.Init(ctx, n.Path(), terraform.InitWithDisableBackend())
-		log.Ctx(ctx).Info().Msg(out)
+		emitPhase(ctx, PhaseInit)
+		out, err := gr.terraformInit(ctx, n, terraform.InitWithDisableBackend())
+		logCommandOutput(ctx, out)
 		if err != nil {
 			return err
 		}
 
 		log.Ctx(ctx).Info().Msgf("Running terraform validate for %s", n.Path())
+		emitPhase(ctx, PhaseValidate)
 
 		var vOpts []terraform.ValidateOption
 
@@ -213,7 +462,7 @@ func (gr *GraphRunner) TerraformValidate(ctx context.Context, dg *graph.Graph) e
 		if err != nil {
 			err = fmt.Errorf("failed to validate %s: %w", n.Identifier(), err)
 		}
-		log.Ctx(ctx).Info().Msg(out)
+		logCommandOutput(ctx, out)
 
 		return err
 	}, true)
@@ -221,10 +470,11 @@ func (gr *GraphRunner) TerraformValidate(ctx context.Context, dg *graph.Graph) e
 
 func (gr *GraphRunner) TerraformPlan(ctx context.Context, dg *graph.Graph, opts *PlanOptions) error {
 	if err := gr.run(ctx, dg, func(ctx context.Context, n graph.Node) error {
-		if !terraformIsInitialized(ctx, n.Path()) || opts.ForceInit {
+		if gr.needsInit(ctx, n) || opts.ForceInit {
 			log.Ctx(ctx).Info().Msgf("Running terraform init for %s", n.Path())
-			out, err := terraform.Init(ctx, n.Path())
-			log.Ctx(ctx).Info().Msg(out)
+			emitPhase(ctx, PhaseInit)
+			out, err := gr.terraformInit(ctx, n)
+			logCommandOutput(ctx, out)
 			if err != nil {
 				return err
 			}
@@ -238,7 +488,7 @@ func (gr *GraphRunner) TerraformPlan(ctx context.Context, dg *graph.Graph, opts
 		}
 
 		if !canPlan {
-			log.Ctx(ctx).Info().Msgf("Skipping planning %s because it has missing outputs", n.Path())
+			emitSkipped(ctx, ReasonMissingOutputs)
 			return err
 		}
 
@@ -250,25 +500,29 @@ func (gr *GraphRunner) TerraformPlan(ctx context.Context, dg *graph.Graph, opts
 			pOpts = append(pOpts, terraform.PlanWithJson())
 		}
 
-		out, err := terraform.Plan(ctx, n.Path(), pOpts...)
+		emitPhase(ctx, PhasePlan)
+		out, err := gr.withRetries(ctx, PhasePlan, func() (string, error) {
+			return terraform.Plan(ctx, n.Path(), pOpts...)
+		})
 		if err != nil {
 			err = fmt.Errorf("failed to plan %s: %w", n.Identifier(), err)
 		}
 
-		if cli.OutputFromContext(ctx) == cli.OutputTypeJSON {
-			logLines, err := cli.ParseTerraformJsonOutput(out)
+		if logErr := logTerraformOutput(ctx, out); logErr != nil {
+			return logErr
+		}
+
+		if err == nil && (opts.Report != nil || hasPolicies(n)) {
+			summary, err := terraform.ShowPlanSummary(ctx, n.Path(), terraform.PlanFile)
 			if err != nil {
-				return err
+				return fmt.Errorf("failed to summarize plan of %s: %w", n.Identifier(), err)
 			}
-			for _, logLine := range logLines {
-				level, err := zerolog.ParseLevel(logLine.Level)
-				if err != nil {
-					level = zerolog.InfoLevel
-				}
-				log.Ctx(ctx).WithLevel(level).Fields(logLine.Remainder).Msg(logLine.Message)
+			if opts.Report != nil {
+				opts.Report.addSummary(n.Identifier(), summary)
+			}
+			if err := checkPolicies(n, summary); err != nil {
+				return err
 			}
-		} else {
-			log.Ctx(ctx).Info().Msg(out)
 		}
 
 		return err
@@ -285,12 +539,15 @@ func (gr *GraphRunner) TerraformProxy(ctx context.Context, dg *graph.Graph, opts
 			return fmt.Errorf("terraform is not initialized for %s. Please run init beforehand", n.Path())
 		}
 
+		emitPhase(ctx, PhaseProxy)
 		out, err := utils.RunTerraform(ctx, n.Path(), opts.Command...)
-		log.Ctx(ctx).Info().Msg(out)
+		logCommandOutput(ctx, out)
 		if err != nil {
-			err = fmt.Errorf("failed to proxy %s: %w", n.Identifier(), err)
+			return fmt.Errorf("failed to proxy %s: %w", n.Identifier(), err)
 		}
-		return err
+
+		// Commands like `init -upgrade` update the lock file
+		return gr.storeLockFile(n)
 	}, opts.IgnoreChangeDetection); err != nil {
 		return err
 	}
@@ -300,10 +557,11 @@ func (gr *GraphRunner) TerraformProxy(ctx context.Context, dg *graph.Graph, opts
 
 func (gr *GraphRunner) TerraformShow(ctx context.Context, dg *graph.Graph, opts *ShowPlanOptions) error {
 	if err := gr.run(ctx, dg, func(ctx context.Context, n graph.Node) error {
-		if !terraformIsInitialized(ctx, n.Path()) || opts.ForceInit {
+		if gr.needsInit(ctx, n) || opts.ForceInit {
 			log.Ctx(ctx).Info().Msgf("Running terraform init for %s", n.Path())
-			out, err := terraform.Init(ctx, n.Path())
-			log.Ctx(ctx).Info().Msg(out)
+			emitPhase(ctx, PhaseInit)
+			out, err := gr.terraformInit(ctx, n)
+			logCommandOutput(ctx, out)
 			if err != nil {
 				return err
 			}
@@ -319,25 +577,14 @@ func (gr *GraphRunner) TerraformShow(ctx context.Context, dg *graph.Graph, opts
 			sOpts = append(sOpts, terraform.ShowWithJson())
 		}
 
+		emitPhase(ctx, PhaseShow)
 		out, err := terraform.Show(ctx, n.Path(), sOpts...)
 		if err != nil {
 			err = fmt.Errorf("failed to show %s: %w", n.Identifier(), err)
 		}
 
-		if cli.OutputFromContext(ctx) == cli.OutputTypeJSON {
-			logLines, err := cli.ParseTerraformJsonOutput(out)
-			if err != nil {
-				return err
-			}
-			for _, logLine := range logLines {
-				level, err := zerolog.ParseLevel(logLine.Level)
-				if err != nil {
-					level = zerolog.InfoLevel
-				}
-				log.Ctx(ctx).WithLevel(level).Fields(logLine.Remainder).Msg(logLine.Message)
-			}
-		} else {
-			log.Ctx(ctx).Info().Msg(out)
+		if logErr := logTerraformOutput(ctx, out); logErr != nil {
+			return logErr
 		}
 		return err
 	}, opts.IgnoreChangeDetection); err != nil {
@@ -349,8 +596,9 @@ func (gr *GraphRunner) TerraformShow(ctx context.Context, dg *graph.Graph, opts
 
 func (gr *GraphRunner) TerraformInit(ctx context.Context, dg *graph.Graph) error {
 	if err := gr.run(ctx, dg, func(ctx context.Context, n graph.Node) error {
-		out, err := terraform.Init(ctx, n.Path())
-		log.Ctx(ctx).Info().Msg(out)
+		emitPhase(ctx, PhaseInit)
+		out, err := gr.terraformInit(ctx, n)
+		logCommandOutput(ctx, out)
 		if err != nil {
 			err = fmt.Errorf("failed to init %s: %w", n.Identifier(), err)
 		}
@@ -361,3 +609,27 @@ func (gr *GraphRunner) TerraformInit(ctx context.Context, dg *graph.Graph) error
 
 	return nil
 }
+
+// TerraformProvidersLock records the checksums of the required providers of all nodes for the given platforms in their
+// lock files
+func (gr *GraphRunner) TerraformProvidersLock(ctx context.Context, dg *graph.Graph, opts *ProvidersLockOptions) error {
+	return gr.run(ctx, dg, func(ctx context.Context, n graph.Node) error {
+		log.Ctx(ctx).Info().Msgf("Running terraform init without backend for %s", n.Path())
+		emitPhase(ctx, PhaseInit)
+		out, err := gr.terraformInit(ctx, n, terraform.InitWithDisableBackend())
+		logCommandOutput(ctx, out)
+		if err != nil {
+			return err
+		}
+
+		log.Ctx(ctx).Info().Msgf("Running terraform providers lock for %s", n.Path())
+		emitPhase(ctx, PhaseProvidersLock)
+		out, err = terraform.ProvidersLock(ctx, n.Path(), opts.Platforms...)
+		logCommandOutput(ctx, out)
+		if err != nil {
+			return fmt.Errorf("failed to lock providers of %s: %w", n.Identifier(), err)
+		}
+
+		return gr.storeLockFile(n)
+	}, true)
+}
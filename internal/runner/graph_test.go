@@ -1,14 +1,26 @@
 package runner
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"github.com/mach-composer/mach-composer-cli/internal/batcher"
 	"github.com/mach-composer/mach-composer-cli/internal/cli"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
 	internalgraph "github.com/mach-composer/mach-composer-cli/internal/graph"
 	"github.com/mach-composer/mach-composer-cli/internal/hash"
+	"github.com/mach-composer/mach-composer-cli/internal/snapshot"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGraphRunnerMultipleLevels(t *testing.T) {
@@ -142,3 +154,332 @@ func TestGraphRunnerError(t *testing.T) {
 	assert.Len(t, cliErr.Errors, 1)
 	assert.Equal(t, assert.AnError, cliErr.Errors[0])
 }
+
+func newSingleComponentGraph() *internalgraph.Graph {
+	project := new(internalgraph.NodeMock)
+	project.On("Identifier").Return("main")
+	project.On("Path").Return("main")
+	project.On("Hash").Return("main", nil)
+	project.On("Type").Return(internalgraph.ProjectType)
+
+	component := new(internalgraph.NodeMock)
+	component.On("Identifier").Return("component-1")
+	component.On("Path").Return("component-1")
+	component.On("Hash").Return("component-1", nil)
+	component.On("Type").Return(internalgraph.SiteComponentType)
+
+	return internalgraph.CreateGraphMock(
+		map[string]internalgraph.Node{
+			"main":        project,
+			"component-1": component,
+		},
+		project,
+		internalgraph.EdgeMock{Source: "main", Target: "component-1"},
+	)
+}
+
+func TestGraphRunnerStreamsOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ctx := cli.ContextWithOutput(context.Background(), cli.OutputTypeConsole)
+	ctx = cli.ContextWithLogWriter(ctx, buf)
+
+	runner := GraphRunner{workers: 1}
+	runner.hash = hash.NewMemoryMapHandler()
+	runner.batch = batcher.NaiveBatchFunc()
+
+	err := runner.run(ctx, newSingleComponentGraph(), func(ctx context.Context, node internalgraph.Node) error {
+		w := utils.OutputWriterFromContext(ctx)
+		require.NotNil(t, w)
+
+		_, err := w.Write([]byte("Refreshing state...\nApply complete!"))
+		require.NoError(t, err)
+
+		// The first complete line is written while the node is still running
+		assert.Contains(t, buf.String(), "[component-1] Refreshing state...")
+		assert.NotContains(t, buf.String(), "Apply complete!")
+		return nil
+	}, false)
+	require.NoError(t, err)
+
+	assert.Contains(t, buf.String(), "[component-1] Apply complete!")
+}
+
+func TestGraphRunnerGroupedOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ctx := cli.ContextWithGroupedOutput(context.Background())
+	ctx = cli.ContextWithLogWriter(ctx, buf)
+
+	runner := GraphRunner{workers: 1}
+	runner.hash = hash.NewMemoryMapHandler()
+	runner.batch = batcher.NaiveBatchFunc()
+
+	err := runner.run(ctx, newSingleComponentGraph(), func(ctx context.Context, node internalgraph.Node) error {
+		assert.Nil(t, utils.OutputWriterFromContext(ctx))
+
+		log.Ctx(ctx).Info().Msg("terraform output")
+		assert.NotContains(t, buf.String(), "terraform output")
+		return nil
+	}, false)
+	require.NoError(t, err)
+
+	assert.Contains(t, buf.String(), "terraform output")
+}
+
+type eventRecorder struct {
+	events []Event
+}
+
+func (r *eventRecorder) HandleEvent(e Event) {
+	r.events = append(r.events, e)
+}
+
+func TestGraphRunnerEmitsEvents(t *testing.T) {
+	runner := GraphRunner{workers: 1}
+	runner.hash = hash.NewMemoryMapHandler()
+	runner.batch = batcher.NaiveBatchFunc()
+
+	recorder := &eventRecorder{}
+	runner.Subscribe(recorder)
+
+	err := runner.run(context.Background(), newSingleComponentGraph(), func(ctx context.Context, node internalgraph.Node) error {
+		_, err := utils.OutputWriterFromContext(ctx).Write([]byte("Apply complete!"))
+		require.NoError(t, err)
+		return assert.AnError
+	}, false)
+	require.Error(t, err)
+
+	var types []EventType
+	for _, e := range recorder.events {
+		types = append(types, e.Type)
+		assert.False(t, e.Time.IsZero())
+	}
+	assert.Equal(t, []EventType{
+		EventNodeQueued, EventBatchStarted, EventNodeStarted, EventNodeOutput, EventNodeFinished, EventBatchFinished,
+	}, types)
+	assert.Equal(t, 1, recorder.events[1].Nodes)
+	assert.Equal(t, "Apply complete!", recorder.events[3].Line)
+	assert.Equal(t, ResultFailed, recorder.events[4].Result)
+	assert.Equal(t, assert.AnError.Error(), recorder.events[4].Error)
+	assert.Equal(t, ResultFailed, recorder.events[5].Result)
+}
+
+func TestGraphRunnerEmitsPhaseAndSkipEvents(t *testing.T) {
+	runner := GraphRunner{workers: 1}
+	runner.hash = hash.NewMemoryMapHandler()
+	runner.batch = batcher.NaiveBatchFunc()
+
+	recorder := &eventRecorder{}
+	runner.Subscribe(recorder)
+
+	err := runner.run(context.Background(), newSingleComponentGraph(), func(ctx context.Context, node internalgraph.Node) error {
+		emitPhase(ctx, PhaseInit)
+		emitSkipped(ctx, ReasonMissingOutputs)
+		return nil
+	}, false)
+	require.NoError(t, err)
+
+	require.Len(t, recorder.events, 7)
+	assert.Equal(t, Event{Type: EventPhaseChanged, Node: "component-1", Phase: PhaseInit}, withoutTime(recorder.events[3]))
+	assert.Equal(t, Event{Type: EventNodeSkipped, Node: "component-1", Reason: ReasonMissingOutputs}, withoutTime(recorder.events[4]))
+	assert.Equal(t, Event{Type: EventNodeFinished, Node: "component-1", Result: ResultSkipped}, withoutTime(recorder.events[5]))
+}
+
+func TestGraphRunnerCountsRetries(t *testing.T) {
+	policy, err := NewRetryPolicy(config.RetryConfig{MaxAttempts: 3, Delay: time.Millisecond})
+	require.NoError(t, err)
+
+	runner := GraphRunner{workers: 1}
+	runner.hash = hash.NewMemoryMapHandler()
+	runner.batch = batcher.NaiveBatchFunc()
+	runner.UseRetryPolicy(policy)
+
+	recorder := &eventRecorder{}
+	runner.Subscribe(recorder)
+
+	err = runner.run(context.Background(), newSingleComponentGraph(), func(ctx context.Context, node internalgraph.Node) error {
+		attempts := 0
+		_, err := runner.withRetries(ctx, PhaseApply, func() (string, error) {
+			attempts++
+			if attempts < 3 {
+				return "Error: Error acquiring the state lock", errors.New("exit status 1")
+			}
+			return "Apply complete!", nil
+		})
+		return err
+	}, false)
+	require.NoError(t, err)
+
+	finished := recorder.events[len(recorder.events)-2]
+	assert.Equal(t, EventNodeFinished, finished.Type)
+	assert.Equal(t, 2, finished.Retries)
+}
+
+func withoutTime(e Event) Event {
+	e.Time = time.Time{}
+	return e
+}
+
+func TestTerraformDestroyRemoved(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as terraform binary")
+	}
+
+	// The fake terraform records the directory and arguments it is run with, with the random part of plan files
+	// removed, and shows an empty plan
+	tmp := t.TempDir()
+	calls := filepath.Join(tmp, "calls")
+	binary := filepath.Join(tmp, "terraform")
+	require.NoError(t, os.WriteFile(binary, []byte(`#!/bin/sh
+echo "$(basename "$PWD") $@" | sed 's/apply-[0-9]*\.plan/apply.plan/g' >> `+calls+`
+if [ "$1" = "show" ]; then
+  echo '{}'
+fi
+`), 0755))
+	ctx := utils.ContextWithTerraformBinary(context.Background(), binary)
+
+	store := snapshot.NewStore(filepath.Join(tmp, "snapshots"))
+	runner := NewGraphRunner(batcher.NaiveBatchFunc(), hash.NewMemoryMapHandler(), 1)
+	runner.UseSnapshotStore(store)
+	recorder := &eventRecorder{}
+	runner.Subscribe(recorder)
+
+	for _, name := range []string{"api", "frontend"} {
+		n := internalgraph.NewRemoved(filepath.Join(tmp, "deployments", name), "site-1/"+name, internalgraph.SiteComponentType)
+		require.NoError(t, os.MkdirAll(n.Path(), 0700))
+		require.NoError(t, os.WriteFile(filepath.Join(n.Path(), name+".tf"), []byte("# "+name), 0600))
+		require.NoError(t, store.Save(n))
+		require.NoError(t, os.RemoveAll(n.Path()))
+	}
+
+	snapshots, err := store.Load()
+	require.NoError(t, err)
+	// The frontend depends on the api, so it is destroyed first
+	removed := []*snapshot.Snapshot{snapshots[1], snapshots[0]}
+
+	err = runner.TerraformDestroyRemoved(ctx, &config.MachConfig{}, removed, &ApplyOptions{AutoApprove: true})
+	require.NoError(t, err)
+
+	// Destroying is planned first, so the plan can be checked against the policies before it is applied
+	data, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	var expected string
+	for _, name := range []string{"frontend", "api"} {
+		expected += name + " init\n" +
+			name + " plan -destroy -out=apply.plan\n" +
+			name + " show -json apply.plan\n" +
+			name + " apply apply.plan\n"
+	}
+	assert.Equal(t, expected, string(data))
+
+	left, err := store.Load()
+	require.NoError(t, err)
+	assert.Empty(t, left)
+	assert.NoDirExists(t, filepath.Join(tmp, "deployments", "frontend"))
+
+	assert.Equal(t, EventNodeQueued, recorder.events[0].Type)
+	assert.Equal(t, "site-1/api", recorder.events[1].Node)
+	assert.Equal(t, "site-1/frontend", recorder.events[3].Node)
+	assert.Equal(t, EventBatchFinished, recorder.events[len(recorder.events)-1].Type)
+	assert.Equal(t, ResultSucceeded, recorder.events[len(recorder.events)-1].Result)
+}
+
+// bucketPlan returns the output of `terraform show -json` for a plan with a single change of a bucket
+func bucketPlan(action string) string {
+	return `{"resource_changes": [{"address": "module.api.aws_s3_bucket.main", "type": "aws_s3_bucket", ` +
+		`"change": {"actions": ["` + action + `"]}}]}`
+}
+
+type approverFunc func() bool
+
+func (f approverFunc) Approve(context.Context, internalgraph.Node, *terraform.PlanSummary) (bool, error) {
+	return f(), nil
+}
+
+// newApplyTestGraph returns a function creating the graph of a project with a single site, and a context with a fake
+// terraform that records the directory and arguments it is run with, with the random part of plan files removed, and
+// shows the given plan
+func newApplyTestGraph(t *testing.T, cfg *config.MachConfig, plan string) (context.Context, func() *internalgraph.Graph, string) {
+	tmp := t.TempDir()
+	calls := filepath.Join(tmp, "calls")
+	binary := filepath.Join(tmp, "terraform")
+	require.NoError(t, os.WriteFile(binary, []byte(`#!/bin/sh
+echo "$(basename "$PWD") $@" | sed 's/apply-[0-9]*\.plan/apply.plan/g' >> `+calls+`
+if [ "$1" = "show" ]; then
+  echo '`+plan+`'
+fi
+`), 0755))
+	ctx := utils.ContextWithTerraformBinary(context.Background(), binary)
+
+	cfg.Filename = "main"
+	cfg.MachComposer.Deployment = config.Deployment{Type: config.DeploymentSite}
+	cfg.Sites[0].Deployment = &config.Deployment{Type: config.DeploymentSite}
+	cfg.Sites[0].Components = config.SiteComponentConfigs{{
+		Name:       "api",
+		Deployment: &config.Deployment{Type: config.DeploymentSite},
+		Definition: &config.ComponentConfig{Name: "api", Source: "git::https://example.com/api.git"},
+	}}
+
+	newGraph := func() *internalgraph.Graph {
+		g, err := internalgraph.ToDeploymentGraph(cfg, filepath.Join(tmp, "deployments"))
+		require.NoError(t, err)
+		for _, n := range g.Vertices() {
+			require.NoError(t, os.MkdirAll(n.Path(), 0700))
+		}
+		return g
+	}
+	return ctx, newGraph, calls
+}
+
+func TestTerraformApplyNotApproved(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as terraform binary")
+	}
+
+	cfg := &config.MachConfig{Sites: []config.SiteConfig{{Identifier: "site-1", Approval: config.ApprovalRequired}}}
+	ctx, newGraph, calls := newApplyTestGraph(t, cfg, bucketPlan("create"))
+	runner := NewGraphRunner(batcher.NaiveBatchFunc(), hash.NewMemoryMapHandler(), 1)
+
+	err := runner.TerraformApply(ctx, newGraph(), &ApplyOptions{Approver: approverFunc(func() bool { return false })})
+	var grouped *cli.GroupedError
+	require.ErrorAs(t, err, &grouped)
+	assert.ErrorContains(t, grouped.Errors[0], "changes to site-1 were not approved")
+
+	// The changes were not applied, so the site is still pending on the next run
+	require.NoError(t, os.Remove(calls))
+	err = runner.TerraformApply(ctx, newGraph(), &ApplyOptions{Approver: approverFunc(func() bool { return true })})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "site-1 apply apply.plan\n")
+}
+
+func TestTerraformApplyPolicyViolation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as terraform binary")
+	}
+
+	cfg := &config.MachConfig{
+		MachComposer: config.MachComposer{Policies: []config.PolicyRule{{
+			Name:          "protect-buckets",
+			ResourceTypes: []string{"aws_s3_bucket"},
+			Actions:       []string{"delete"},
+		}}},
+		Sites: []config.SiteConfig{{Identifier: "site-1"}},
+	}
+	ctx, newGraph, calls := newApplyTestGraph(t, cfg, bucketPlan("delete"))
+	runner := NewGraphRunner(batcher.NaiveBatchFunc(), hash.NewMemoryMapHandler(), 1)
+
+	for i := 0; i < 2; i++ {
+		err := runner.TerraformApply(ctx, newGraph(), &ApplyOptions{AutoApprove: true})
+		var grouped *cli.GroupedError
+		require.ErrorAs(t, err, &grouped)
+		assert.ErrorContains(t, grouped.Errors[0], "protect-buckets: module.api.aws_s3_bucket.main will be deleted")
+	}
+
+	// The blocked changes were never applied, so the site is planned again on the next run instead of skipped
+	data, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "site-1 plan -out=apply.plan\n"))
+	assert.NotContains(t, string(data), "site-1 apply")
+}
@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+)
+
+const lockFileName = ".terraform.lock.hcl"
+
+// committedLockFile returns the path of the lock file of the node in the lock directory
+func (gr *GraphRunner) committedLockFile(n graph.Node) string {
+	return filepath.Join(gr.lockDir, n.Identifier(), lockFileName)
+}
+
+// lockFileChanged returns whether the committed lock file of the node differs from the lock file it was initialized
+// with, in which case terraform needs to be initialized again
+func (gr *GraphRunner) lockFileChanged(n graph.Node) bool {
+	if gr.lockDir == "" {
+		return false
+	}
+
+	committed, err := utils.AFS.ReadFile(gr.committedLockFile(n))
+	if err != nil {
+		return false
+	}
+	current, err := utils.AFS.ReadFile(filepath.Join(n.Path(), lockFileName))
+	if err != nil {
+		return true
+	}
+	return !bytes.Equal(committed, current)
+}
+
+// restoreLockFile copies the committed lock file of the node into the node directory, so terraform installs the
+// locked provider versions
+func (gr *GraphRunner) restoreLockFile(n graph.Node) error {
+	if gr.lockDir == "" {
+		return nil
+	}
+
+	data, err := utils.AFS.ReadFile(gr.committedLockFile(n))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read lock file of %s: %w", n.Identifier(), err)
+	}
+	return utils.AFS.WriteFile(filepath.Join(n.Path(), lockFileName), data, 0644)
+}
+
+// storeLockFile copies the lock file of the node to the lock directory when terraform changed it
+func (gr *GraphRunner) storeLockFile(n graph.Node) error {
+	if gr.lockDir == "" {
+		return nil
+	}
+
+	data, err := utils.AFS.ReadFile(filepath.Join(n.Path(), lockFileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read lock file of %s: %w", n.Identifier(), err)
+	}
+
+	target := gr.committedLockFile(n)
+	if committed, err := utils.AFS.ReadFile(target); err == nil && bytes.Equal(committed, data) {
+		return nil
+	}
+
+	if err := utils.AFS.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return utils.AFS.WriteFile(target, data, 0644)
+}
@@ -0,0 +1,52 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	internalgraph "github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+)
+
+func TestLockFiles(t *testing.T) {
+	afs := utils.AFS
+	utils.AFS = &afero.Afero{Fs: afero.NewMemMapFs()}
+	defer func() { utils.AFS = afs }()
+
+	n := new(internalgraph.NodeMock)
+	n.On("Identifier").Return("my-site/my-component")
+	n.On("Path").Return("deployments/my-site/my-component")
+
+	gr := &GraphRunner{}
+	assert.False(t, gr.lockFileChanged(n))
+	require.NoError(t, gr.restoreLockFile(n))
+	require.NoError(t, gr.storeLockFile(n))
+
+	gr.UseLockDir("locks")
+
+	// Without a lock file in the node directory nothing is stored
+	require.NoError(t, gr.storeLockFile(n))
+	exists, err := utils.AFS.Exists("locks/my-site/my-component/.terraform.lock.hcl")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// A lock file created by terraform init is stored in the lock directory
+	require.NoError(t, utils.AFS.WriteFile("deployments/my-site/my-component/.terraform.lock.hcl", []byte("v1"), 0644))
+	require.NoError(t, gr.storeLockFile(n))
+	data, err := utils.AFS.ReadFile("locks/my-site/my-component/.terraform.lock.hcl")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(data))
+	assert.False(t, gr.lockFileChanged(n))
+
+	// An updated committed lock file requires a new init, which restores it
+	require.NoError(t, utils.AFS.WriteFile("locks/my-site/my-component/.terraform.lock.hcl", []byte("v2"), 0644))
+	assert.True(t, gr.lockFileChanged(n))
+	require.NoError(t, gr.restoreLockFile(n))
+	data, err = utils.AFS.ReadFile("deployments/my-site/my-component/.terraform.lock.hcl")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(data))
+	assert.False(t, gr.lockFileChanged(n))
+}
@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+)
+
+// policyScope contains the policy rules that apply to a node, and the values the rules are matched against
+type policyScope struct {
+	rules       []config.PolicyRule
+	environment string
+	site        string
+	component   string
+}
+
+func policyScopeFor(n graph.Node) policyScope {
+	switch node := n.(type) {
+	case *graph.Site:
+		return policyScope{
+			rules:       node.ProjectConfig.MachComposer.Policies,
+			environment: node.ProjectConfig.Global.Environment,
+			site:        node.SiteConfig.Identifier,
+		}
+	case *graph.SiteComponent:
+		return policyScope{
+			rules:       node.ProjectConfig.MachComposer.Policies,
+			environment: node.ProjectConfig.Global.Environment,
+			site:        node.SiteConfig.Identifier,
+			component:   node.SiteComponentConfig.Name,
+		}
+	case *graph.Removed:
+		return policyScope{
+			rules:       node.ProjectConfig.MachComposer.Policies,
+			environment: node.ProjectConfig.Global.Environment,
+			site:        node.Site,
+			component:   node.Component,
+		}
+	}
+	return policyScope{}
+}
+
+func hasPolicies(n graph.Node) bool {
+	return len(policyScopeFor(n).rules) > 0
+}
+
+// checkPolicies returns an error describing every planned change of the node that violates a policy rule
+func checkPolicies(n graph.Node, summary *terraform.PlanSummary) error {
+	scope := policyScopeFor(n)
+
+	var violations []string
+	for _, c := range summary.Changes {
+		component := scope.component
+		if component == "" {
+			component = moduleName(c.Address)
+		}
+
+		for _, rule := range scope.rules {
+			if !ruleMatches(rule, scope.environment, scope.site, component, c) {
+				continue
+			}
+
+			violation := fmt.Sprintf("%s: %s will be %s", rule.Name, c.Address, actionVerb(c.Action))
+			if rule.Message != "" {
+				violation += ". " + rule.Message
+			}
+			violations = append(violations, violation)
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("planned changes of %s violate policies:\n  - %s",
+		n.Identifier(), strings.Join(violations, "\n  - "))
+}
+
+func ruleMatches(rule config.PolicyRule, environment, site, component string, c terraform.ResourceChange) bool {
+	return matchesAny(rule.ResourceTypes, c.Type) &&
+		actionMatches(rule.Actions, c.Action) &&
+		matchesAny(rule.Sites, site) &&
+		matchesAny(rule.Components, component) &&
+		matchesAny(rule.Environments, environment)
+}
+
+// matchesAny returns true if the value matches one of the patterns, or if there are no patterns
+func matchesAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func actionMatches(actions []string, action terraform.Action) bool {
+	if len(actions) == 0 {
+		return true
+	}
+	for _, a := range actions {
+		if terraform.Action(a) == action {
+			return true
+		}
+		if terraform.Action(a) == terraform.ActionDelete && action == terraform.ActionReplace {
+			return true
+		}
+	}
+	return false
+}
+
+func actionVerb(a terraform.Action) string {
+	switch a {
+	case terraform.ActionCreate:
+		return "created"
+	case terraform.ActionUpdate:
+		return "updated"
+	case terraform.ActionDelete:
+		return "deleted"
+	case terraform.ActionReplace:
+		return "replaced"
+	}
+	return string(a)
+}
@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	internalgraph "github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+)
+
+func newPolicyProject(rules ...config.PolicyRule) config.MachConfig {
+	return config.MachConfig{
+		MachComposer: config.MachComposer{Policies: rules},
+		Global:       config.GlobalConfig{Environment: "production"},
+	}
+}
+
+func TestCheckPoliciesSite(t *testing.T) {
+	n := &internalgraph.Site{
+		ProjectConfig: newPolicyProject(config.PolicyRule{
+			Name:          "protect-buckets",
+			ResourceTypes: []string{"aws_s3_*"},
+			Actions:       []string{"delete"},
+			Sites:         []string{"*-prod"},
+			Components:    []string{"storage"},
+			Environments:  []string{"production"},
+			Message:       "Buckets cannot be removed in production",
+		}),
+		SiteConfig: config.SiteConfig{Identifier: "my-site-prod"},
+	}
+
+	err := checkPolicies(n, &terraform.PlanSummary{Changes: []terraform.ResourceChange{
+		{Address: "module.storage.aws_s3_bucket.main", Type: "aws_s3_bucket", Action: terraform.ActionReplace},
+		{Address: "module.storage.aws_s3_bucket.logs", Type: "aws_s3_bucket", Action: terraform.ActionUpdate},
+		{Address: "module.other.aws_s3_bucket.main", Type: "aws_s3_bucket", Action: terraform.ActionDelete},
+	}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(),
+		"protect-buckets: module.storage.aws_s3_bucket.main will be replaced. Buckets cannot be removed in production")
+	assert.NotContains(t, err.Error(), "aws_s3_bucket.logs")
+	assert.NotContains(t, err.Error(), "module.other")
+}
+
+func TestCheckPoliciesSiteComponent(t *testing.T) {
+	rule := config.PolicyRule{
+		Name:          "protect-projects",
+		ResourceTypes: []string{"commercetools_project_settings"},
+		Actions:       []string{"delete", "replace"},
+		Components:    []string{"commercetools"},
+	}
+	summary := &terraform.PlanSummary{Changes: []terraform.ResourceChange{
+		{Address: "commercetools_project_settings.project", Type: "commercetools_project_settings", Action: terraform.ActionDelete},
+	}}
+
+	n := &internalgraph.SiteComponent{
+		ProjectConfig:       newPolicyProject(rule),
+		SiteConfig:          config.SiteConfig{Identifier: "my-site"},
+		SiteComponentConfig: config.SiteComponentConfig{Name: "commercetools"},
+	}
+	assert.Error(t, checkPolicies(n, summary))
+
+	n.SiteComponentConfig.Name = "other"
+	assert.NoError(t, checkPolicies(n, summary))
+
+	n.SiteComponentConfig.Name = "commercetools"
+	n.ProjectConfig.Global.Environment = "test"
+	rule.Environments = []string{"production"}
+	n.ProjectConfig.MachComposer.Policies = []config.PolicyRule{rule}
+	assert.NoError(t, checkPolicies(n, summary))
+}
+
+func TestHasPolicies(t *testing.T) {
+	assert.False(t, hasPolicies(&internalgraph.Site{}))
+	assert.False(t, hasPolicies(&internalgraph.Project{}))
+	assert.True(t, hasPolicies(&internalgraph.Site{
+		ProjectConfig: newPolicyProject(config.PolicyRule{Name: "rule"}),
+	}))
+}
@@ -0,0 +1,212 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/rs/zerolog/log"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+)
+
+// ProviderCache shares downloaded terraform providers between all nodes through TF_PLUGIN_CACHE_DIR. Terraform does
+// not support writing to the cache concurrently, so an init that might download a provider runs on its own. Once the
+// providers of a node are cached, inits that only need cached providers run in parallel.
+type ProviderCache struct {
+	dir string
+	// inits is held for writing by inits that might write to the cache, and for reading by inits that only use cached
+	// providers
+	inits sync.RWMutex
+	mu    sync.Mutex
+	warm  map[string]bool
+
+	coldInits []time.Duration
+	warmInits []time.Duration
+}
+
+func NewProviderCache(dir string) (*ProviderCache, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create provider cache directory: %w", err)
+	}
+
+	return &ProviderCache{
+		dir:  dir,
+		warm: make(map[string]bool),
+	}, nil
+}
+
+// init runs terraform init for the node with the shared provider cache
+func (c *ProviderCache) init(ctx context.Context, n graph.Node, opts ...terraform.InitOption) (string, error) {
+	keys := requiredProviders(n.Path())
+
+	cold := c.lockInit(keys)
+	if cold {
+		defer c.inits.Unlock()
+	} else {
+		defer c.inits.RUnlock()
+	}
+
+	env := map[string]string{"TF_PLUGIN_CACHE_DIR": c.dir}
+	// Without a lock file terraform would not use the cache, as it cannot verify the cached providers against it.
+	// Allowing it weakens the checksum guarantees of terraform, so it is not done silently.
+	if _, err := os.Stat(filepath.Join(n.Path(), ".terraform.lock.hcl")); err != nil {
+		log.Ctx(ctx).Warn().Msgf("%s has no lock file, so cached providers are used without verifying their checksums "+
+			"against the registry. Configure `lock_dir` to keep lock files between runs, or use --no-provider-cache",
+			n.Path())
+		env["TF_PLUGIN_CACHE_MAY_BREAK_DEPENDENCY_LOCK_FILE"] = "true"
+	}
+
+	start := time.Now()
+	out, err := terraform.Init(utils.ContextWithEnv(ctx, env), n.Path(), opts...)
+	if err != nil {
+		return out, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		c.warm[key] = true
+	}
+	if cold {
+		c.coldInits = append(c.coldInits, time.Since(start))
+	} else {
+		c.warmInits = append(c.warmInits, time.Since(start))
+	}
+	return out, nil
+}
+
+// lockInit waits until the init of a node with the given providers can run, and returns whether it might write to the
+// cache. Such an init holds the write lock; other inits hold the read lock.
+func (c *ProviderCache) lockInit(keys []string) bool {
+	if !c.isCold(keys) {
+		c.inits.RLock()
+		return false
+	}
+
+	c.inits.Lock()
+	// Another node might have cached the providers while waiting for the lock
+	if c.isCold(keys) {
+		return true
+	}
+	c.inits.Unlock()
+	c.inits.RLock()
+	return false
+}
+
+// isCold returns whether any of the providers is not cached yet. Nodes of which the providers are unknown are always
+// cold, as they might need any provider.
+func (c *ProviderCache) isCold(keys []string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if key == "*" || !c.warm[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary describes the init time that is saved by the cache. The time saved is estimated as the difference between
+// the average init with and without cached providers.
+func (c *ProviderCache) Summary() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.coldInits) == 0 || len(c.warmInits) == 0 {
+		return ""
+	}
+
+	saved := (average(c.coldInits) - average(c.warmInits)) * time.Duration(len(c.warmInits))
+	if saved < 0 {
+		saved = 0
+	}
+	return fmt.Sprintf("Provider cache saved approximately %s of terraform init time (%d of %d inits used cached providers)",
+		saved.Round(time.Second), len(c.warmInits), len(c.warmInits)+len(c.coldInits))
+}
+
+func average(durations []time.Duration) time.Duration {
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return total / time.Duration(len(durations))
+}
+
+// requiredProviders returns the providers the terraform files in the given directory require, in the form
+// `source@version`. If the files cannot be read a single key is returned, so these inits are serialized.
+func requiredProviders(path string) []string {
+	files, err := filepath.Glob(filepath.Join(path, "*.tf"))
+	if err != nil || len(files) == 0 {
+		return []string{"*"}
+	}
+
+	keys := map[string]bool{}
+	parser := hclparse.NewParser()
+	for _, filename := range files {
+		file, diags := parser.ParseHCLFile(filename)
+		if diags.HasErrors() {
+			log.Debug().Msgf("Failed to parse %s for required providers: %s", filename, diags.Error())
+			return []string{"*"}
+		}
+
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+		for _, block := range body.Blocks {
+			if block.Type != "terraform" {
+				continue
+			}
+			for _, child := range block.Body.Blocks {
+				if child.Type != "required_providers" {
+					continue
+				}
+				for name, attr := range child.Body.Attributes {
+					keys[providerKey(name, attr)] = true
+				}
+			}
+		}
+	}
+
+	result := make([]string, 0, len(keys))
+	for key := range keys {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func providerKey(name string, attr *hclsyntax.Attribute) string {
+	source, version := "hashicorp/"+name, ""
+
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || !value.Type().IsObjectType() {
+		return source + "@"
+	}
+	if value.Type().HasAttribute("source") {
+		if v := value.GetAttr("source"); v.Type() == cty.String && v.IsKnown() && !v.IsNull() {
+			source = v.AsString()
+		}
+	}
+	if value.Type().HasAttribute("version") {
+		if v := value.GetAttr("version"); v.Type() == cty.String && v.IsKnown() && !v.IsNull() {
+			version = v.AsString()
+		}
+	}
+	return source + "@" + version
+}
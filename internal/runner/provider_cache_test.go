@@ -0,0 +1,199 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	internalgraph "github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+)
+
+const testProviders = `
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+    commercetools = {
+      source = "labd/commercetools"
+    }
+    random = {}
+  }
+}
+`
+
+func TestRequiredProviders(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(testProviders), 0644))
+
+	assert.Equal(t, []string{
+		"hashicorp/aws@~> 5.0",
+		"hashicorp/random@",
+		"labd/commercetools@",
+	}, requiredProviders(dir))
+
+	assert.Equal(t, []string{"*"}, requiredProviders(t.TempDir()))
+}
+
+func TestProviderCacheSerializesColdInits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as terraform binary")
+	}
+
+	// The fake terraform fails when it downloads the provider while another init is running
+	tmp := t.TempDir()
+	binary := filepath.Join(tmp, "terraform")
+	require.NoError(t, os.WriteFile(binary, []byte(`#!/bin/sh
+if [ ! -f "$TF_PLUGIN_CACHE_DIR/provider" ]; then
+  mkdir "$TF_PLUGIN_CACHE_DIR/running" || exit 1
+  sleep 0.2
+  touch "$TF_PLUGIN_CACHE_DIR/provider"
+  rmdir "$TF_PLUGIN_CACHE_DIR/running"
+fi
+echo "cache: $TF_PLUGIN_CACHE_DIR"
+`), 0755))
+
+	cache, err := NewProviderCache(filepath.Join(tmp, "providers"))
+	require.NoError(t, err)
+	ctx := utils.ContextWithTerraformBinary(context.Background(), binary)
+
+	var nodes []*internalgraph.NodeMock
+	for i := 0; i < 3; i++ {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(testProviders), 0644))
+		n := new(internalgraph.NodeMock)
+		n.On("Path").Return(dir)
+		nodes = append(nodes, n)
+	}
+
+	wg := sync.WaitGroup{}
+	for _, n := range nodes {
+		wg.Add(1)
+		go func(n *internalgraph.NodeMock) {
+			defer wg.Done()
+			out, err := cache.init(ctx, n)
+			assert.NoError(t, err)
+			assert.Contains(t, out, "cache: "+filepath.Join(tmp, "providers"))
+		}(n)
+	}
+	wg.Wait()
+
+	assert.Len(t, cache.coldInits, 1)
+	assert.Len(t, cache.warmInits, 2)
+}
+
+func TestProviderCacheWithoutLockFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as terraform binary")
+	}
+
+	tmp := t.TempDir()
+	binary := filepath.Join(tmp, "terraform")
+	require.NoError(t, os.WriteFile(binary, []byte(`#!/bin/sh
+echo "may break: $TF_PLUGIN_CACHE_MAY_BREAK_DEPENDENCY_LOCK_FILE"
+`), 0755))
+
+	cache, err := NewProviderCache(filepath.Join(tmp, "providers"))
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	ctx := zerolog.New(buf).WithContext(utils.ContextWithTerraformBinary(context.Background(), binary))
+
+	dir := t.TempDir()
+	n := new(internalgraph.NodeMock)
+	n.On("Path").Return(dir)
+
+	// Terraform only uses the cache without a lock file when it may break the lock file, which is warned about
+	out, err := cache.init(ctx, n)
+	require.NoError(t, err)
+	assert.Contains(t, out, "may break: true")
+	assert.Contains(t, buf.String(), dir+" has no lock file")
+
+	buf.Reset()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".terraform.lock.hcl"), nil, 0644))
+	out, err = cache.init(ctx, n)
+	require.NoError(t, err)
+	assert.Contains(t, out, "may break: \n")
+	assert.Empty(t, buf.String())
+}
+
+func TestProviderCacheSerializesColdAndWarmInits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as terraform binary")
+	}
+
+	// The fake terraform fails when a cold init runs at the same time as any other init
+	tmp := t.TempDir()
+	binary := filepath.Join(tmp, "terraform")
+	require.NoError(t, os.WriteFile(binary, []byte(`#!/bin/sh
+if [ -f cold ]; then
+  mkdir "$TF_PLUGIN_CACHE_DIR/cold" || exit 1
+  [ -z "$(ls "$TF_PLUGIN_CACHE_DIR/warm" 2>/dev/null)" ] || exit 1
+  sleep 0.2
+  rmdir "$TF_PLUGIN_CACHE_DIR/cold"
+else
+  [ ! -d "$TF_PLUGIN_CACHE_DIR/cold" ] || exit 1
+  mkdir -p "$TF_PLUGIN_CACHE_DIR/warm/$$"
+  sleep 0.2
+  rmdir "$TF_PLUGIN_CACHE_DIR/warm/$$"
+fi
+`), 0755))
+
+	cache, err := NewProviderCache(filepath.Join(tmp, "providers"))
+	require.NoError(t, err)
+	cache.warm["hashicorp/aws@5.0.0"] = true
+	ctx := utils.ContextWithTerraformBinary(context.Background(), binary)
+
+	var nodes []*internalgraph.NodeMock
+	for _, provider := range []string{"aws", "random", "aws"} {
+		dir := t.TempDir()
+		main := fmt.Sprintf(`terraform {
+  required_providers {
+    %s = { version = "5.0.0" }
+  }
+}`, provider)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(main), 0644))
+		if provider != "aws" {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "cold"), nil, 0644))
+		}
+		n := new(internalgraph.NodeMock)
+		n.On("Path").Return(dir)
+		nodes = append(nodes, n)
+	}
+
+	wg := sync.WaitGroup{}
+	for _, n := range nodes {
+		wg.Add(1)
+		go func(n *internalgraph.NodeMock) {
+			defer wg.Done()
+			_, err := cache.init(ctx, n)
+			assert.NoError(t, err)
+		}(n)
+	}
+	wg.Wait()
+
+	assert.Len(t, cache.coldInits, 1)
+	assert.Len(t, cache.warmInits, 2)
+}
+
+func TestProviderCacheSummary(t *testing.T) {
+	cache := &ProviderCache{}
+	assert.Empty(t, cache.Summary())
+
+	cache.coldInits = []time.Duration{30 * time.Second}
+	cache.warmInits = []time.Duration{5 * time.Second, 5 * time.Second}
+	assert.Equal(t,
+		"Provider cache saved approximately 50s of terraform init time (2 of 3 inits used cached providers)",
+		cache.Summary())
+}
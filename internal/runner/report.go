@@ -0,0 +1,274 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+)
+
+type PlanStatus string
+
+const (
+	PlanStatusChanges   PlanStatus = "changes"
+	PlanStatusNoChanges PlanStatus = "no_changes"
+	PlanStatusSkipped   PlanStatus = "skipped"
+	PlanStatusFailed    PlanStatus = "failed"
+)
+
+// PlanReportEntry contains the planned changes of a single component of a site. Nodes that deploy a whole site are
+// split up per component based on the module address of the resources. The json representation should be kept
+// backwards compatible.
+type PlanReportEntry struct {
+	Site      string                     `json:"site"`
+	Component string                     `json:"component,omitempty"`
+	Node      string                     `json:"node"`
+	Status    PlanStatus                 `json:"status"`
+	Reason    string                     `json:"reason,omitempty"`
+	Add       int                        `json:"add"`
+	Change    int                        `json:"change"`
+	Destroy   int                        `json:"destroy"`
+	Retries   int                        `json:"retries,omitempty"`
+	Changes   []terraform.ResourceChange `json:"changes,omitempty"`
+}
+
+type planReportNode struct {
+	status  PlanStatus
+	reason  string
+	summary *terraform.PlanSummary
+}
+
+// PlanReport collects the planned changes of every node of a plan run, so they can be rendered as a single document.
+// It should be subscribed to the runner to record the nodes that are skipped or fail.
+type PlanReport struct {
+	nodes   map[string]*planReportNode
+	retries map[string]int
+	mu      sync.Mutex
+}
+
+func NewPlanReport() *PlanReport {
+	return &PlanReport{
+		nodes:   make(map[string]*planReportNode),
+		retries: make(map[string]int),
+	}
+}
+
+func (r *PlanReport) HandleEvent(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch e.Type {
+	case EventNodeSkipped:
+		r.nodes[e.Node] = &planReportNode{status: PlanStatusSkipped, reason: e.Reason}
+	case EventNodeRetried:
+		r.retries[e.Node]++
+	case EventNodeFinished:
+		if e.Result == ResultFailed {
+			r.nodes[e.Node] = &planReportNode{status: PlanStatusFailed, reason: e.Error}
+		}
+	}
+}
+
+func (r *PlanReport) addSummary(node string, summary *terraform.PlanSummary) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := PlanStatusNoChanges
+	if summary.HasChanges() {
+		status = PlanStatusChanges
+	}
+	r.nodes[node] = &planReportNode{status: status, summary: summary}
+}
+
+// Entries returns the entries of the report, ordered by site and component
+func (r *PlanReport) Entries() []PlanReportEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var entries []PlanReportEntry
+	for identifier, n := range r.nodes {
+		site, component, _ := strings.Cut(identifier, "/")
+
+		if n.summary == nil || !n.summary.HasChanges() {
+			entries = append(entries, PlanReportEntry{
+				Site: site, Component: component, Node: identifier, Status: n.status, Reason: n.reason,
+				Retries: r.retries[identifier],
+			})
+			continue
+		}
+
+		grouped := make(map[string]*terraform.PlanSummary)
+		for _, c := range n.summary.Changes {
+			name := component
+			if name == "" {
+				name = moduleName(c.Address)
+			}
+			if grouped[name] == nil {
+				grouped[name] = &terraform.PlanSummary{}
+			}
+			grouped[name].Changes = append(grouped[name].Changes, c)
+		}
+
+		for name, summary := range grouped {
+			add, change, destroy := summary.Counts()
+			entries = append(entries, PlanReportEntry{
+				Site:      site,
+				Component: name,
+				Node:      identifier,
+				Status:    PlanStatusChanges,
+				Add:       add,
+				Change:    change,
+				Destroy:   destroy,
+				Changes:   summary.Changes,
+				Retries:   r.retries[identifier],
+			})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Site != entries[j].Site {
+			return entries[i].Site < entries[j].Site
+		}
+		return entries[i].Component < entries[j].Component
+	})
+	return entries
+}
+
+// moduleName returns the name of the component module a resource belongs to, or an empty string for resources that
+// are not part of a module
+func moduleName(address string) string {
+	if !strings.HasPrefix(address, "module.") {
+		return ""
+	}
+	name, _, _ := strings.Cut(strings.TrimPrefix(address, "module."), ".")
+	return name
+}
+
+func (r *PlanReport) RenderJSON(w io.Writer) error {
+	entries := r.Entries()
+	if entries == nil {
+		entries = []PlanReportEntry{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Entries []PlanReportEntry `json:"entries"`
+	}{Entries: entries})
+}
+
+// RenderMarkdown writes the report as a Markdown document that is suitable to post as a pull request comment
+func (r *PlanReport) RenderMarkdown(w io.Writer) error {
+	entries := r.Entries()
+
+	var b strings.Builder
+	b.WriteString("## Plan summary\n\n")
+
+	var destroying []string
+	for _, e := range entries {
+		if e.Destroy > 0 {
+			destroying = append(destroying, fmt.Sprintf("`%s`", entryName(e)))
+		}
+	}
+	if len(destroying) > 0 {
+		b.WriteString("> [!WARNING]\n")
+		b.WriteString(fmt.Sprintf("> Resources will be destroyed in %s\n\n", strings.Join(destroying, ", ")))
+	}
+
+	if len(entries) == 0 {
+		b.WriteString("No components were planned.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	b.WriteString("| Site | Component | Status | Add | Change | Destroy |\n")
+	b.WriteString("|------|-----------|--------|----:|-------:|--------:|\n")
+	for _, e := range entries {
+		destroy := fmt.Sprint(e.Destroy)
+		if e.Destroy > 0 {
+			destroy = fmt.Sprintf("**%d** :warning:", e.Destroy)
+		}
+		b.WriteString(fmt.Sprintf("| %s | %s | %s | %d | %d | %s |\n",
+			e.Site, e.Component, statusText(e), e.Add, e.Change, destroy))
+	}
+
+	for _, e := range entries {
+		if e.Status != PlanStatusChanges {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("\n<details><summary><code>%s</code>: %d to add, %d to change, %d to destroy</summary>\n\n",
+			entryName(e), e.Add, e.Change, e.Destroy))
+		b.WriteString("```diff\n")
+		for _, c := range e.Changes {
+			b.WriteString(diffLine(c))
+			b.WriteString("\n")
+		}
+		b.WriteString("```\n\n</details>\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// RenderGithubAnnotations writes a GitHub Actions warning annotation for every component in which resources will be
+// destroyed
+func (r *PlanReport) RenderGithubAnnotations(w io.Writer) error {
+	for _, e := range r.Entries() {
+		if e.Destroy == 0 {
+			continue
+		}
+		_, err := fmt.Fprintf(w, "::warning title=Resources will be destroyed::%d resources will be destroyed in %s\n",
+			e.Destroy, entryName(e))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func entryName(e PlanReportEntry) string {
+	if e.Component == "" {
+		return e.Site
+	}
+	return e.Site + "/" + e.Component
+}
+
+func statusText(e PlanReportEntry) string {
+	var status string
+	switch e.Status {
+	case PlanStatusChanges:
+		status = "changes"
+	case PlanStatusNoChanges:
+		status = "no changes"
+	case PlanStatusFailed:
+		status = "failed"
+	default:
+		status = "skipped"
+		if e.Reason != "" {
+			status = fmt.Sprintf("skipped (%s)", e.Reason)
+		}
+	}
+
+	if e.Retries > 0 {
+		status += fmt.Sprintf(" after %d retries", e.Retries)
+	}
+	return status
+}
+
+// diffLine renders a resource change as a line of a diff block, so destroyed resources are highlighted
+func diffLine(c terraform.ResourceChange) string {
+	switch c.Action {
+	case terraform.ActionCreate:
+		return "+ " + c.Address
+	case terraform.ActionDelete:
+		return "- " + c.Address
+	case terraform.ActionReplace:
+		return "- " + c.Address + " (replace)"
+	default:
+		return "! " + c.Address
+	}
+}
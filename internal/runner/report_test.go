@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+)
+
+func newTestPlanReport() *PlanReport {
+	r := NewPlanReport()
+	r.addSummary("site-1", &terraform.PlanSummary{Changes: []terraform.ResourceChange{
+		{Address: "module.component-1.aws_s3_bucket.main", Action: terraform.ActionCreate},
+		{Address: "module.component-2.aws_iam_role.main", Action: terraform.ActionDelete},
+		{Address: "module.component-2.aws_lambda_function.main", Action: terraform.ActionUpdate},
+	}})
+	r.addSummary("site-2/component-1", &terraform.PlanSummary{})
+	r.HandleEvent(Event{Type: EventNodeSkipped, Node: "site-2/component-2", Reason: ReasonNoChanges})
+	r.HandleEvent(Event{Type: EventNodeFinished, Node: "site-2/component-2", Result: ResultSkipped})
+	r.HandleEvent(Event{Type: EventNodeFinished, Node: "site-3", Result: ResultFailed, Error: "boom"})
+	return r
+}
+
+func TestPlanReportEntries(t *testing.T) {
+	entries := newTestPlanReport().Entries()
+	require.Len(t, entries, 5)
+
+	assert.Equal(t, "site-1", entries[0].Site)
+	assert.Equal(t, "component-1", entries[0].Component)
+	assert.Equal(t, PlanStatusChanges, entries[0].Status)
+	assert.Equal(t, 1, entries[0].Add)
+
+	assert.Equal(t, "component-2", entries[1].Component)
+	assert.Equal(t, 0, entries[1].Add)
+	assert.Equal(t, 1, entries[1].Change)
+	assert.Equal(t, 1, entries[1].Destroy)
+
+	assert.Equal(t, PlanStatusNoChanges, entries[2].Status)
+	assert.Equal(t, PlanStatusSkipped, entries[3].Status)
+	assert.Equal(t, ReasonNoChanges, entries[3].Reason)
+	assert.Equal(t, PlanStatusFailed, entries[4].Status)
+	assert.Equal(t, "boom", entries[4].Reason)
+}
+
+func TestPlanReportRenderMarkdown(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, newTestPlanReport().RenderMarkdown(buf))
+
+	assert.Equal(t, "## Plan summary\n\n"+
+		"> [!WARNING]\n"+
+		"> Resources will be destroyed in `site-1/component-2`\n\n"+
+		"| Site | Component | Status | Add | Change | Destroy |\n"+
+		"|------|-----------|--------|----:|-------:|--------:|\n"+
+		"| site-1 | component-1 | changes | 1 | 0 | 0 |\n"+
+		"| site-1 | component-2 | changes | 0 | 1 | **1** :warning: |\n"+
+		"| site-2 | component-1 | no changes | 0 | 0 | 0 |\n"+
+		"| site-2 | component-2 | skipped (no changes) | 0 | 0 | 0 |\n"+
+		"| site-3 |  | failed | 0 | 0 | 0 |\n"+
+		"\n<details><summary><code>site-1/component-1</code>: 1 to add, 0 to change, 0 to destroy</summary>\n\n"+
+		"```diff\n+ module.component-1.aws_s3_bucket.main\n```\n\n</details>\n"+
+		"\n<details><summary><code>site-1/component-2</code>: 0 to add, 1 to change, 1 to destroy</summary>\n\n"+
+		"```diff\n- module.component-2.aws_iam_role.main\n! module.component-2.aws_lambda_function.main\n```\n\n</details>\n",
+		buf.String())
+}
+
+func TestPlanReportRenderJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, newTestPlanReport().RenderJSON(buf))
+
+	var result struct {
+		Entries []PlanReportEntry `json:"entries"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &result))
+	assert.Equal(t, newTestPlanReport().Entries(), result.Entries)
+}
+
+func TestPlanReportRenderGithubAnnotations(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, newTestPlanReport().RenderGithubAnnotations(buf))
+
+	assert.Equal(t,
+		"::warning title=Resources will be destroyed::1 resources will be destroyed in site-1/component-2\n",
+		buf.String())
+}
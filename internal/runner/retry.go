@@ -0,0 +1,114 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+)
+
+// DefaultRetryPatterns match the output of terraform commands that failed because of state lock contention, rate
+// limits or server errors
+var DefaultRetryPatterns = []string{
+	`Error acquiring the state lock`,
+	`(?i)too many requests`,
+	`(?i)rate limit`,
+	`(?i)(status|status code|statuscode|http)[ :=]*(429|5\d\d)\b`,
+	`(?i)connection reset by peer`,
+	`(?i)TLS handshake timeout`,
+}
+
+// RetryPolicy decides whether a failed terraform command is run again, and how long to wait before doing so
+type RetryPolicy struct {
+	MaxAttempts int
+	Delay       time.Duration
+	MaxDelay    time.Duration
+	Patterns    []*regexp.Regexp
+}
+
+func NewRetryPolicy(cfg config.RetryConfig) (*RetryPolicy, error) {
+	p := &RetryPolicy{
+		MaxAttempts: cfg.MaxAttempts,
+		Delay:       cfg.Delay,
+		MaxDelay:    cfg.MaxDelay,
+	}
+	if p.MaxAttempts < 1 {
+		p.MaxAttempts = 1
+	}
+	if p.Delay == 0 {
+		p.Delay = 10 * time.Second
+	}
+	if p.MaxDelay == 0 {
+		p.MaxDelay = 5 * time.Minute
+	}
+
+	patterns := cfg.Patterns
+	if len(patterns) == 0 {
+		patterns = DefaultRetryPatterns
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry pattern %s: %w", pattern, err)
+		}
+		p.Patterns = append(p.Patterns, re)
+	}
+	return p, nil
+}
+
+// match returns the pattern that matches the output of a failed command, or an empty string if the failure should
+// not be retried
+func (p *RetryPolicy) match(out string) string {
+	for _, re := range p.Patterns {
+		if re.MatchString(out) {
+			return re.String()
+		}
+	}
+	return ""
+}
+
+// delay returns the time to wait after the given attempt failed
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Delay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+// withRetries runs a terraform command until it succeeds, fails with an error that is not retryable, or the maximum
+// number of attempts is reached. The output and error of the last attempt are returned.
+func (gr *GraphRunner) withRetries(ctx context.Context, phase Phase, fn func() (string, error)) (string, error) {
+	p := gr.retries
+	for attempt := 1; ; attempt++ {
+		out, err := fn()
+		if err == nil || p == nil || attempt >= p.MaxAttempts {
+			return out, err
+		}
+
+		pattern := p.match(out + "\n" + err.Error())
+		if pattern == "" {
+			return out, err
+		}
+
+		delay := p.delay(attempt)
+		log.Ctx(ctx).Warn().Msgf("Terraform %s failed with a retryable error (matched %s), retrying in %s (attempt %d of %d)",
+			phase, pattern, delay, attempt+1, p.MaxAttempts)
+		if e := nodeEventsFromContext(ctx); e != nil {
+			e.emit(Event{Type: EventNodeRetried, Phase: phase, Attempt: attempt + 1, Reason: pattern, Err: err})
+		}
+
+		select {
+		case <-ctx.Done():
+			return out, err
+		case <-time.After(delay):
+		}
+	}
+}
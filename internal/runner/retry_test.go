@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+)
+
+type recordingSubscriber struct {
+	events []Event
+}
+
+func (s *recordingSubscriber) HandleEvent(e Event) {
+	s.events = append(s.events, e)
+}
+
+func TestNewRetryPolicy(t *testing.T) {
+	p, err := NewRetryPolicy(config.RetryConfig{MaxAttempts: 4, Delay: time.Second, MaxDelay: 5 * time.Second})
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Second, p.delay(1))
+	assert.Equal(t, 2*time.Second, p.delay(2))
+	assert.Equal(t, 4*time.Second, p.delay(3))
+	assert.Equal(t, 5*time.Second, p.delay(4))
+
+	assert.Equal(t, "Error acquiring the state lock", p.match("Error: Error acquiring the state lock"))
+	assert.NotEmpty(t, p.match("unexpected response: status code 503"))
+	assert.NotEmpty(t, p.match("StatusCode: 429, Too Many Requests"))
+	assert.Empty(t, p.match("Error: Unsupported argument"))
+
+	_, err = NewRetryPolicy(config.RetryConfig{MaxAttempts: 2, Patterns: []string{"("}})
+	assert.Error(t, err)
+}
+
+func TestWithRetries(t *testing.T) {
+	policy, err := NewRetryPolicy(config.RetryConfig{MaxAttempts: 3, Delay: time.Millisecond})
+	require.NoError(t, err)
+
+	gr := &GraphRunner{}
+	gr.UseRetryPolicy(policy)
+	recorder := &recordingSubscriber{}
+	gr.Subscribe(recorder)
+	ctx := contextWithNodeEvents(context.Background(), &nodeEvents{bus: &gr.events, node: "my-site"})
+
+	attempts := 0
+	out, err := gr.withRetries(ctx, PhaseApply, func() (string, error) {
+		attempts++
+		if attempts < 3 {
+			return "Error: Error acquiring the state lock", errors.New("exit status 1")
+		}
+		return "Apply complete!", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Apply complete!", out)
+	assert.Equal(t, 3, attempts)
+
+	require.Len(t, recorder.events, 2)
+	assert.Equal(t, EventNodeRetried, recorder.events[0].Type)
+	assert.Equal(t, PhaseApply, recorder.events[0].Phase)
+	assert.Equal(t, 2, recorder.events[0].Attempt)
+	assert.Equal(t, 3, recorder.events[1].Attempt)
+
+	// Failures that do not match are not retried
+	attempts = 0
+	_, err = gr.withRetries(ctx, PhaseApply, func() (string, error) {
+		attempts++
+		return "Error: Unsupported argument", errors.New("exit status 1")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+
+	// The error of the last attempt is returned
+	attempts = 0
+	_, err = gr.withRetries(ctx, PhasePlan, func() (string, error) {
+		attempts++
+		return "Error: 429 Too Many Requests", errors.New("exit status 1")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 3, attempts)
+}
@@ -2,7 +2,9 @@ package runner
 
 import (
 	"context"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/snapshot"
 )
 
 type ApplyOptions struct {
@@ -10,12 +12,16 @@ type ApplyOptions struct {
 	IgnoreChangeDetection bool
 	Destroy               bool
 	AutoApprove           bool
+	// Approver approves the changes of sites that require approval
+	Approver Approver
 }
 
 type PlanOptions struct {
 	ForceInit             bool
 	IgnoreChangeDetection bool
 	Lock                  bool
+	// Report collects the planned changes of every node when set
+	Report *PlanReport
 }
 
 type ProxyOptions struct {
@@ -29,10 +35,17 @@ type ShowPlanOptions struct {
 	NoColor               bool
 }
 
+type ProvidersLockOptions struct {
+	// Platforms to record checksums for, in the form os_arch. Defaults to the current platform
+	Platforms []string
+}
+
 type Runner interface {
 	TerraformApply(ctx context.Context, dg *graph.Graph, opts *ApplyOptions) error
+	TerraformDestroyRemoved(ctx context.Context, cfg *config.MachConfig, removed []*snapshot.Snapshot, opts *ApplyOptions) error
 	TerraformInit(ctx context.Context, dg *graph.Graph) error
 	TerraformPlan(ctx context.Context, dg *graph.Graph, opts *PlanOptions) error
+	TerraformProvidersLock(ctx context.Context, dg *graph.Graph, opts *ProvidersLockOptions) error
 	TerraformProxy(ctx context.Context, dg *graph.Graph, opts *ProxyOptions) error
 	TerraformShow(ctx context.Context, dg *graph.Graph, opts *ShowPlanOptions) error
 }
@@ -0,0 +1,180 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	ReasonNoChanges      = "no changes"
+	ReasonMissingOutputs = "missing outputs"
+)
+
+// LogSubscriber writes the progress of a graph run to the logger of the context it is created with
+type LogSubscriber struct {
+	logger *zerolog.Logger
+}
+
+func NewLogSubscriber(ctx context.Context) *LogSubscriber {
+	return &LogSubscriber{logger: log.Ctx(ctx)}
+}
+
+func (s *LogSubscriber) HandleEvent(e Event) {
+	switch e.Type {
+	case EventBatchStarted:
+		s.logger.Info().Msgf("Running batch %d with %d nodes", e.Batch, e.Nodes)
+	case EventBatchFinished:
+		if e.Result == ResultSucceeded {
+			s.logger.Info().Msgf("Finished batch %d", e.Batch)
+		}
+	case EventNodeSkipped:
+		s.logger.Info().Msgf("Skipping %s because it has %s", e.Node, e.Reason)
+	case EventPhaseChanged:
+		s.logger.Debug().Msgf("Running terraform %s for %s", e.Phase, e.Node)
+	case EventNodeFinished:
+		if e.Retries > 0 {
+			s.logger.Info().Msgf("Finished %s: %s after %d retries", e.Node, e.Result, e.Retries)
+			return
+		}
+		s.logger.Debug().Msgf("Finished %s: %s", e.Node, e.Result)
+	}
+}
+
+// JSONLinesSubscriber writes every event as a single line of json
+type JSONLinesSubscriber struct {
+	w io.Writer
+}
+
+func NewJSONLinesSubscriber(w io.Writer) *JSONLinesSubscriber {
+	return &JSONLinesSubscriber{w: w}
+}
+
+func (s *JSONLinesSubscriber) HandleEvent(e Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to encode %s event", e.Type)
+		return
+	}
+	if _, err := s.w.Write(append(data, '\n')); err != nil {
+		log.Warn().Err(err).Msgf("Failed to write %s event", e.Type)
+	}
+}
+
+// WebhookSubscriber posts every event as json to a URL. Events are sent in the background so a slow endpoint does not
+// slow down the run; Close waits until all events are sent. Output events are not sent at all, as there are too many of
+// them. When the queue is full phase and retry events are dropped, while the run waits to queue the lifecycle events of
+// batches and nodes, so a consumer sees every node that starts finish.
+type WebhookSubscriber struct {
+	url    string
+	client *http.Client
+	queue  chan Event
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	closed  bool
+	dropped int
+}
+
+func NewWebhookSubscriber(url string) *WebhookSubscriber {
+	s := &WebhookSubscriber{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		queue:  make(chan Event, 100),
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for e := range s.queue {
+			if err := s.post(e); err != nil {
+				log.Warn().Err(err).Msgf("Failed to send %s event to %s", e.Type, s.url)
+			}
+		}
+	}()
+
+	return s
+}
+
+func (s *WebhookSubscriber) HandleEvent(e Event) {
+	if e.Type == EventNodeOutput {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+
+	if !droppable(e.Type) {
+		// Waiting is bounded by the timeout of the requests that send the queued events
+		s.queue <- e
+		return
+	}
+
+	// Events are emitted while the runner holds a lock, so they should not wait on the endpoint
+	select {
+	case s.queue <- e:
+	default:
+		s.dropped++
+		log.Warn().Msgf("Dropped %s event of %s for %s because it could not keep up", e.Type, e.Node, s.url)
+	}
+}
+
+// droppable returns whether events of the type can be dropped when the webhook cannot keep up. The lifecycle events of
+// batches and nodes never are.
+func droppable(t EventType) bool {
+	return t == EventPhaseChanged || t == EventNodeRetried
+}
+
+// Dropped returns the number of phase and retry events that were not sent because the queue was full
+func (s *WebhookSubscriber) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+func (s *WebhookSubscriber) post(e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Close waits until all queued events are sent. Events received after closing are ignored.
+func (s *WebhookSubscriber) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	if dropped := s.Dropped(); dropped > 0 {
+		log.Warn().Msgf("Dropped %d phase and retry events for %s because it could not keep up", dropped, s.url)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONLinesSubscriber(t *testing.T) {
+	buf := &bytes.Buffer{}
+	s := NewJSONLinesSubscriber(buf)
+
+	s.HandleEvent(Event{
+		Type:  EventBatchStarted,
+		Time:  time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Batch: 1,
+		Nodes: 2,
+	})
+	s.HandleEvent(Event{
+		Type:   EventNodeFinished,
+		Time:   time.Date(2024, 1, 1, 12, 0, 5, 0, time.UTC),
+		Batch:  1,
+		Node:   "site-1/component-1",
+		Result: ResultFailed,
+		Error:  "boom",
+		Err:    errors.New("boom"),
+	})
+
+	assert.Equal(t, `{"type":"batch_started","time":"2024-01-01T12:00:00Z","batch":1,"nodes":2}
+{"type":"node_finished","time":"2024-01-01T12:00:05Z","batch":1,"node":"site-1/component-1","result":"failed","error":"boom"}
+`, buf.String())
+}
+
+func TestWebhookSubscriber(t *testing.T) {
+	var mu sync.Mutex
+	var received []Event
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var e Event
+		require.NoError(t, json.Unmarshal(body, &e))
+
+		mu.Lock()
+		received = append(received, e)
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	s := NewWebhookSubscriber(ts.URL)
+	s.HandleEvent(Event{Type: EventNodeStarted, Node: "component-1"})
+	s.HandleEvent(Event{Type: EventNodeOutput, Node: "component-1", Line: "Refreshing state..."})
+	s.HandleEvent(Event{Type: EventNodeFinished, Node: "component-1", Result: ResultSucceeded})
+	require.NoError(t, s.Close())
+
+	require.Len(t, received, 2)
+	assert.Equal(t, EventNodeStarted, received[0].Type)
+	assert.Equal(t, EventNodeFinished, received[1].Type)
+	assert.Equal(t, ResultSucceeded, received[1].Result)
+}
+
+func TestWebhookSubscriberDropsEventsWhenFull(t *testing.T) {
+	var mu sync.Mutex
+	received := map[EventType]int{}
+	release := make(chan struct{})
+	blocked := make(chan struct{})
+	var once sync.Once
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { close(blocked) })
+		<-release
+
+		var e Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		mu.Lock()
+		received[e.Type]++
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	s := NewWebhookSubscriber(ts.URL)
+	// The first event is taken from the queue and blocks on the endpoint; the queue holds 100 more events
+	s.HandleEvent(Event{Type: EventBatchStarted, Batch: 0, Nodes: 1})
+	<-blocked
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 110; i++ {
+			s.HandleEvent(Event{Type: EventPhaseChanged, Node: "component-1", Phase: PhasePlan})
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("HandleEvent blocked on a full queue")
+	}
+	assert.Equal(t, 10, s.Dropped())
+
+	// Lifecycle events are never dropped, the run waits until they can be queued
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		s.HandleEvent(Event{Type: EventNodeFinished, Node: "component-1", Result: ResultSucceeded})
+	}()
+	select {
+	case <-finished:
+		t.Fatal("HandleEvent dropped a lifecycle event on a full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-finished
+	require.NoError(t, s.Close())
+	assert.Equal(t, 1, received[EventNodeFinished])
+
+	// Events received after closing are ignored
+	s.HandleEvent(Event{Type: EventNodeFinished, Node: "component-1"})
+	require.NoError(t, s.Close())
+}
+
+func TestLogSubscriberUsesContextLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ctx := zerolog.New(buf).WithContext(context.Background())
+
+	s := NewLogSubscriber(ctx)
+	s.HandleEvent(Event{Type: EventNodeSkipped, Node: "component-1", Reason: ReasonNoChanges})
+
+	assert.Contains(t, buf.String(), "Skipping component-1 because it has no changes")
+}
@@ -2,20 +2,99 @@ package runner
 
 import (
 	"context"
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
 	"github.com/mach-composer/mach-composer-cli/internal/utils"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"io"
 	"os"
 	"path/filepath"
+	"sync"
 )
 
+// syncWriter serializes writes, so log lines of nodes that run in parallel are not mixed up
+type syncWriter struct {
+	io.Writer
+	mu sync.Mutex
+}
+
+func (w *syncWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.Writer.Write(p)
+}
+
+// logTerraformLine logs a single line of streamed terraform output. Lines are prefixed with the node identifier on the
+// console, and parsed as terraform log lines when json output is used
+func logTerraformLine(ctx context.Context, n graph.Node, line string) {
+	if cli.OutputFromContext(ctx) != cli.OutputTypeJSON {
+		log.Ctx(ctx).Info().Msgf("[%s] %s", n.Identifier(), line)
+		return
+	}
+
+	if line == "" {
+		return
+	}
+	logLines, err := cli.ParseTerraformJsonOutput(line)
+	if err != nil {
+		// Not all commands support json output, so log these lines as-is
+		log.Ctx(ctx).Info().Msg(line)
+		return
+	}
+	for _, logLine := range logLines {
+		level, err := zerolog.ParseLevel(logLine.Level)
+		if err != nil {
+			level = zerolog.InfoLevel
+		}
+		log.Ctx(ctx).WithLevel(level).Fields(logLine.Remainder).Msg(logLine.Message)
+	}
+}
+
+// logCommandOutput logs the output of a finished command, unless it was already streamed while running
+func logCommandOutput(ctx context.Context, out string) {
+	if utils.OutputWriterFromContext(ctx) != nil {
+		return
+	}
+	log.Ctx(ctx).Info().Msg(out)
+}
+
+// logTerraformOutput logs the output of a finished terraform command that supports json output, unless it was
+// already streamed while running
+func logTerraformOutput(ctx context.Context, out string) error {
+	if utils.OutputWriterFromContext(ctx) != nil {
+		return nil
+	}
+
+	if cli.OutputFromContext(ctx) != cli.OutputTypeJSON {
+		log.Ctx(ctx).Info().Msg(out)
+		return nil
+	}
+
+	logLines, err := cli.ParseTerraformJsonOutput(out)
+	if err != nil {
+		return err
+	}
+	for _, logLine := range logLines {
+		level, err := zerolog.ParseLevel(logLine.Level)
+		if err != nil {
+			level = zerolog.InfoLevel
+		}
+		log.Ctx(ctx).WithLevel(level).Fields(logLine.Remainder).Msg(logLine.Message)
+	}
+	return nil
+}
+
+// terraformIsInitialized returns whether terraform init ran for the given path. The lock file alone is not enough, as
+// it can be copied in from the lock directory before terraform is initialized.
 func terraformIsInitialized(ctx context.Context, path string) bool {
-	tfLockFile := filepath.Join(path, ".terraform.lock.hcl")
-	if _, err := os.Stat(tfLockFile); err != nil {
-		if os.IsNotExist(err) {
-			return false
+	for _, name := range []string{".terraform.lock.hcl", ".terraform"} {
+		if _, err := os.Stat(filepath.Join(path, name)); err != nil {
+			if os.IsNotExist(err) {
+				return false
+			}
+			log.Ctx(ctx).Fatal().Err(err)
 		}
-		log.Ctx(ctx).Fatal().Err(err)
 	}
 	return true
 }
@@ -0,0 +1,339 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+)
+
+const defaultSnapshotDir = ".mach-composer/snapshots"
+
+// Snapshot holds the generated terraform of a node as it was last applied, so its resources can still be destroyed
+// after the node is removed from the configuration
+type Snapshot struct {
+	Identifier string     `json:"identifier"`
+	Type       graph.Type `json:"type"`
+	Path       string     `json:"path"`
+	// Site is the identifier of the site of the node, and Component the name of the site component, if any
+	Site      string `json:"site,omitempty"`
+	Component string `json:"component,omitempty"`
+	// Approval is the approval setting of the site when the node was applied
+	Approval config.ApprovalType `json:"approval,omitempty"`
+	// Dependencies are the identifiers of the nodes this node depended on when it was applied
+	Dependencies []string          `json:"dependencies,omitempty"`
+	Files        map[string]string `json:"files"`
+}
+
+// Node returns a node that can be used to run terraform in the directory of the snapshot. The policies of the given
+// configuration apply to it. When its site is still part of the configuration the current approval setting of the
+// site is used, otherwise the setting it had when the node was applied.
+func (s *Snapshot) Node(cfg *config.MachConfig) *graph.Removed {
+	n := graph.NewRemoved(s.Path, s.Identifier, s.Type)
+	n.Site = s.Site
+	n.Component = s.Component
+	n.Approval = s.Approval
+	if cfg == nil {
+		return n
+	}
+
+	n.ProjectConfig = *cfg
+	for _, site := range cfg.Sites {
+		if site.Identifier == s.Site {
+			n.Approval = site.Approval
+		}
+	}
+	return n
+}
+
+// Restore writes the files of the snapshot to its directory. A stored plan is removed, as terraform would apply it
+// instead of destroying the resources.
+func (s *Snapshot) Restore() error {
+	if err := os.MkdirAll(s.Path, 0700); err != nil {
+		return fmt.Errorf("error creating directory structure: %w", err)
+	}
+	if err := os.Remove(filepath.Join(s.Path, terraform.PlanFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stored plan of %s: %w", s.Identifier, err)
+	}
+	for name, content := range s.Files {
+		filename := filepath.Join(s.Path, filepath.FromSlash(name))
+		// Variable files can be in a directory of their own
+		if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+			return fmt.Errorf("error creating directory structure: %w", err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0600); err != nil {
+			return fmt.Errorf("failed to restore %s of %s: %w", name, s.Identifier, err)
+		}
+	}
+	return nil
+}
+
+// Store keeps a snapshot for every node that is applied, as one json file per node
+type Store struct {
+	dir string
+}
+
+func Factory(_ *config.MachConfig) *Store {
+	dir := os.Getenv("MC_SNAPSHOT_DIR")
+	if dir == "" {
+		dir = defaultSnapshotDir
+	}
+
+	return NewStore(dir)
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+func (s *Store) filename(identifier string) string {
+	return filepath.Join(s.dir, url.PathEscape(identifier)+".json")
+}
+
+// Save stores the generated terraform files and the copied variable files of the node, with the identifiers of the
+// nodes it depends on. The terraform working directory, state and plan files are not part of the snapshot.
+func (s *Store) Save(n graph.Node) error {
+	files, err := filepath.Glob(filepath.Join(n.Path(), "*.tf"))
+	if err != nil {
+		return err
+	}
+	names := []string{".terraform.lock.hcl"}
+	for _, filename := range files {
+		names = append(names, filepath.Base(filename))
+	}
+
+	snapshot := &Snapshot{
+		Identifier: n.Identifier(),
+		Type:       n.Type(),
+		Path:       n.Path(),
+		Files:      map[string]string{},
+	}
+	switch node := n.(type) {
+	case *graph.Site:
+		snapshot.Site = node.SiteConfig.Identifier
+		snapshot.Approval = node.SiteConfig.Approval
+		names = append(names, variableFiles(&node.ProjectConfig, n.Identifier())...)
+	case *graph.SiteComponent:
+		snapshot.Site = node.SiteConfig.Identifier
+		snapshot.Component = node.SiteComponentConfig.Name
+		snapshot.Approval = node.SiteConfig.Approval
+		names = append(names, variableFiles(&node.ProjectConfig, n.Identifier())...)
+	}
+
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(n.Path(), name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		snapshot.Files[filepath.ToSlash(name)] = string(data)
+	}
+
+	parents, err := n.Parents()
+	if err != nil {
+		return err
+	}
+	for _, p := range parents {
+		if p.Type() != graph.ProjectType {
+			snapshot.Dependencies = append(snapshot.Dependencies, p.Identifier())
+		}
+	}
+	sort.Strings(snapshot.Dependencies)
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("error creating directory structure: %w", err)
+	}
+	return os.WriteFile(s.filename(n.Identifier()), data, 0600)
+}
+
+// variableFiles returns the names of the encrypted variable files that are copied to the directory of the node when
+// it is generated, relative to that directory
+func variableFiles(cfg *config.MachConfig, identifier string) []string {
+	if cfg.Variables == nil {
+		return nil
+	}
+
+	var result []string
+	for _, source := range cfg.Variables.GetEncryptedSources(identifier) {
+		result = append(result, source.Filename)
+	}
+	return result
+}
+
+// Load returns all stored snapshots, ordered by identifier
+func (s *Store) Load() ([]*Snapshot, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Snapshot
+	for _, filename := range files {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		snapshot := &Snapshot{}
+		if err := json.Unmarshal(data, snapshot); err != nil {
+			return nil, fmt.Errorf("failed to parse snapshot %s: %w", filename, err)
+		}
+		result = append(result, snapshot)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Identifier < result[j].Identifier
+	})
+	return result, nil
+}
+
+// Delete removes the snapshot of the node with the given identifier
+func (s *Store) Delete(identifier string) error {
+	err := os.Remove(s.filename(identifier))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Removed returns the snapshots of nodes that are not part of the given deployment graph anymore, ordered so nodes are
+// destroyed before the nodes they depend on. Site components that are now deployed as part of their site are not
+// returned, as their resources are managed by the site.
+func (s *Store) Removed(g *graph.Graph) ([]*Snapshot, error) {
+	snapshots, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	current := map[string]bool{}
+	nested := map[string]bool{}
+	for _, n := range g.Vertices() {
+		current[n.Identifier()] = true
+		if site, ok := n.(*graph.Site); ok {
+			for _, c := range site.NestedNodes {
+				nested[c.Identifier()] = true
+			}
+		}
+	}
+
+	for _, path := range untracked(g, snapshots) {
+		log.Warn().Msgf("%s is not part of the config, but it has no snapshot, so its resources cannot be destroyed "+
+			"with `mach-composer apply --destroy-removed`. Destroy them with terraform, and remove the directory", path)
+	}
+
+	var removed []*Snapshot
+	for _, snapshot := range snapshots {
+		if current[snapshot.Identifier] {
+			continue
+		}
+		if nested[snapshot.Identifier] {
+			log.Warn().Msgf("Not destroying %s, as it is now deployed as part of its site. Move its resources to the "+
+				"state of the site and remove %s", snapshot.Identifier, s.filename(snapshot.Identifier))
+			continue
+		}
+		removed = append(removed, snapshot)
+	}
+
+	return destroyOrder(removed)
+}
+
+// untracked returns the generated directories that are neither part of the graph nor have a snapshot, like the
+// directories of nodes that were removed before snapshots were stored. Their resources cannot be destroyed
+// automatically.
+func untracked(g *graph.Graph, snapshots []*Snapshot) []string {
+	known := map[string]bool{}
+	var root string
+	for _, n := range g.Vertices() {
+		known[filepath.Clean(n.Path())] = true
+		if n.Type() == graph.ProjectType {
+			root = filepath.Clean(n.Path())
+		}
+	}
+	for _, snapshot := range snapshots {
+		known[filepath.Clean(snapshot.Path)] = true
+	}
+	if root == "" {
+		return nil
+	}
+
+	var result []string
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".terraform" {
+			return filepath.SkipDir
+		}
+		if known[path] {
+			return nil
+		}
+
+		if files, _ := filepath.Glob(filepath.Join(path, "*.tf")); len(files) > 0 {
+			result = append(result, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return result
+}
+
+// destroyOrder orders the snapshots so every node comes before the nodes it depends on
+func destroyOrder(snapshots []*Snapshot) ([]*Snapshot, error) {
+	dependents := map[string]int{}
+	byIdentifier := map[string]*Snapshot{}
+	for _, snapshot := range snapshots {
+		byIdentifier[snapshot.Identifier] = snapshot
+	}
+	for _, snapshot := range snapshots {
+		for _, d := range snapshot.Dependencies {
+			if byIdentifier[d] != nil {
+				dependents[d]++
+			}
+		}
+	}
+
+	var result []*Snapshot
+	done := map[string]bool{}
+	for len(result) < len(snapshots) {
+		progress := false
+		for _, snapshot := range snapshots {
+			if done[snapshot.Identifier] || dependents[snapshot.Identifier] > 0 {
+				continue
+			}
+			done[snapshot.Identifier] = true
+			progress = true
+			result = append(result, snapshot)
+			for _, d := range snapshot.Dependencies {
+				if byIdentifier[d] != nil {
+					dependents[d]--
+				}
+			}
+		}
+		if !progress {
+			var left []string
+			for _, snapshot := range snapshots {
+				if !done[snapshot.Identifier] {
+					left = append(left, snapshot.Identifier)
+				}
+			}
+			return nil, fmt.Errorf("removed nodes depend on each other in a cycle: %s", strings.Join(left, ", "))
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,188 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+)
+
+func testConfig(components ...config.SiteComponentConfig) *config.MachConfig {
+	return &config.MachConfig{
+		Filename: "main",
+		MachComposer: config.MachComposer{
+			Deployment: config.Deployment{Type: config.DeploymentSite},
+		},
+		Sites: []config.SiteConfig{
+			{
+				Identifier: "site-1",
+				Deployment: &config.Deployment{Type: config.DeploymentSite},
+				Components: components,
+			},
+		},
+	}
+}
+
+func component(name string, deploymentType config.DeploymentType, dependsOn ...string) config.SiteComponentConfig {
+	return config.SiteComponentConfig{
+		Name:       name,
+		Deployment: &config.Deployment{Type: deploymentType},
+		DependsOn:  dependsOn,
+	}
+}
+
+func TestStoreSaveAndRestore(t *testing.T) {
+	out := t.TempDir()
+	cfg := testConfig(
+		component("api", config.DeploymentSiteComponent),
+		component("frontend", config.DeploymentSiteComponent, "api"),
+	)
+	g, err := graph.ToDeploymentGraph(cfg, out)
+	require.NoError(t, err)
+
+	n, err := g.Vertex(filepath.Join(out, "main", "site-1", "frontend"))
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(n.Path(), ".terraform"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(n.Path(), "frontend.tf"), []byte("# frontend"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(n.Path(), "terraform.tfstate"), []byte("{}"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(n.Path(), ".terraform", "terraform.tfstate"), []byte("{}"), 0600))
+
+	store := NewStore(filepath.Join(t.TempDir(), "snapshots"))
+	require.NoError(t, store.Save(n))
+
+	snapshots, err := store.Load()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, "site-1/frontend", snapshots[0].Identifier)
+	assert.Equal(t, graph.SiteComponentType, snapshots[0].Type)
+	assert.Equal(t, "site-1", snapshots[0].Site)
+	assert.Equal(t, "frontend", snapshots[0].Component)
+	assert.Equal(t, []string{"site-1/api"}, snapshots[0].Dependencies)
+	assert.Equal(t, map[string]string{"frontend.tf": "# frontend"}, snapshots[0].Files)
+
+	require.NoError(t, os.RemoveAll(n.Path()))
+	require.NoError(t, snapshots[0].Restore())
+	data, err := os.ReadFile(filepath.Join(n.Path(), "frontend.tf"))
+	require.NoError(t, err)
+	assert.Equal(t, "# frontend", string(data))
+
+	require.NoError(t, store.Delete("site-1/frontend"))
+	require.NoError(t, store.Delete("site-1/frontend"))
+	snapshots, err = store.Load()
+	require.NoError(t, err)
+	assert.Empty(t, snapshots)
+}
+
+func TestStoreRemoved(t *testing.T) {
+	out := t.TempDir()
+	store := NewStore(filepath.Join(t.TempDir(), "snapshots"))
+
+	previous, err := graph.ToDeploymentGraph(testConfig(
+		component("api", config.DeploymentSiteComponent),
+		component("frontend", config.DeploymentSiteComponent, "api"),
+		component("search", config.DeploymentSiteComponent),
+		component("cms", config.DeploymentSiteComponent),
+	), out)
+	require.NoError(t, err)
+	for _, n := range previous.Vertices() {
+		if n.Type() != graph.ProjectType {
+			require.NoError(t, store.Save(n))
+		}
+	}
+
+	// The api and frontend are removed, and the cms is now deployed as part of the site
+	current, err := graph.ToDeploymentGraph(testConfig(
+		component("search", config.DeploymentSiteComponent),
+		component("cms", config.DeploymentSite),
+	), out)
+	require.NoError(t, err)
+
+	removed, err := store.Removed(current)
+	require.NoError(t, err)
+
+	var identifiers []string
+	for _, s := range removed {
+		identifiers = append(identifiers, s.Identifier)
+	}
+	assert.Equal(t, []string{"site-1/frontend", "site-1/api"}, identifiers)
+}
+
+func TestStoreRestoreVariableFiles(t *testing.T) {
+	s := &Snapshot{
+		Identifier: "site-1",
+		Path:       filepath.Join(t.TempDir(), "site-1"),
+		Files: map[string]string{
+			"site.tf":                "# site",
+			"secrets/variables.yaml": "ENC[AES256_GCM,data:abc]",
+		},
+	}
+	require.NoError(t, s.Restore())
+
+	data, err := os.ReadFile(filepath.Join(s.Path, "secrets", "variables.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "ENC[AES256_GCM,data:abc]", string(data))
+}
+
+func TestSnapshotNode(t *testing.T) {
+	s := &Snapshot{
+		Identifier: "site-1/api",
+		Type:       graph.SiteComponentType,
+		Site:       "site-1",
+		Component:  "api",
+		Approval:   config.ApprovalRequired,
+	}
+
+	n := s.Node(nil)
+	assert.Equal(t, "api", n.Component)
+	assert.Equal(t, config.ApprovalRequired, n.Approval)
+
+	// The current approval setting of the site is used while the site is part of the config
+	n = s.Node(testConfig())
+	assert.Equal(t, "site-1", n.Site)
+	assert.Equal(t, config.ApprovalType(""), n.Approval)
+}
+
+func TestUntracked(t *testing.T) {
+	out := t.TempDir()
+	cfg := testConfig(component("api", config.DeploymentSiteComponent))
+	g, err := graph.ToDeploymentGraph(cfg, out)
+	require.NoError(t, err)
+
+	for _, name := range []string{"api", "frontend", "search"} {
+		dir := filepath.Join(out, "main", "site-1", name)
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, ".terraform"), 0700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name+".tf"), []byte("# "+name), 0600))
+	}
+	snapshots := []*Snapshot{{Identifier: "site-1/search", Path: filepath.Join(out, "main", "site-1", "search")}}
+
+	// The api is part of the graph and the search has a snapshot, but the frontend was removed before snapshots were
+	// stored
+	assert.Equal(t, []string{filepath.Join(out, "main", "site-1", "frontend")}, untracked(g, snapshots))
+}
+
+func TestDestroyOrder(t *testing.T) {
+	result, err := destroyOrder([]*Snapshot{
+		{Identifier: "site-1"},
+		{Identifier: "site-1/a", Dependencies: []string{"site-1"}},
+		{Identifier: "site-1/b", Dependencies: []string{"site-1", "site-1/a"}},
+		{Identifier: "site-1/c", Dependencies: []string{"site-1/other"}},
+	})
+	require.NoError(t, err)
+
+	var identifiers []string
+	for _, s := range result {
+		identifiers = append(identifiers, s.Identifier)
+	}
+	assert.Equal(t, []string{"site-1/b", "site-1/c", "site-1/a", "site-1"}, identifiers)
+
+	_, err = destroyOrder([]*Snapshot{
+		{Identifier: "a", Dependencies: []string{"b"}},
+		{Identifier: "b", Dependencies: []string{"a"}},
+	})
+	assert.ErrorContains(t, err, "cycle")
+}
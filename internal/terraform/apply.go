@@ -3,7 +3,6 @@ package terraform
 import (
 	"context"
 	"github.com/mach-composer/mach-composer-cli/internal/utils"
-	"strings"
 )
 
 type ApplyOption func([]string) []string
@@ -26,6 +25,7 @@ func ApplyWithJson() ApplyOption {
 	}
 }
 
+// Apply plans and applies the changes. Use ApplyPlan to apply a stored plan
 func Apply(ctx context.Context, path string, opts ...ApplyOption) (string, error) {
 	args := []string{"apply"}
 
@@ -33,14 +33,18 @@ func Apply(ctx context.Context, path string, opts ...ApplyOption) (string, error
 		args = opt(args)
 	}
 
-	// If there is a plan then we should use it.
-	planFilename, err := hasTerraformPlan(path)
-	if err != nil {
-		return "", err
-	}
-	if planFilename != "" {
-		args = append(args, strings.TrimPrefix(planFilename, path+"/"))
+	return utils.RunTerraform(ctx, path, args...)
+}
+
+// ApplyPlan applies the plan stored in the given file, relative to the path. A stored plan is applied without asking
+// for confirmation, and already contains whether resources are destroyed.
+func ApplyPlan(ctx context.Context, path, filename string, opts ...ApplyOption) (string, error) {
+	args := []string{"apply"}
+
+	for _, opt := range opts {
+		args = opt(args)
 	}
+	args = append(args, filename)
 
 	return utils.RunTerraform(ctx, path, args...)
 }
@@ -0,0 +1,279 @@
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/adrg/xdg"
+	"github.com/hashicorp/go-getter"
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/hashicorp/go-version"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	BinaryTerraform = "terraform"
+	BinaryOpenTofu  = "tofu"
+)
+
+// releaseSource describes where the releases of a binary are published. The urls of the archive and checksums contain
+// placeholders for the version, os and arch.
+type releaseSource struct {
+	Index     string
+	Archive   string
+	Checksums string
+}
+
+var releaseSources = map[string]releaseSource{
+	BinaryTerraform: {
+		Index:     "https://releases.hashicorp.com/terraform/index.json",
+		Archive:   "https://releases.hashicorp.com/terraform/{version}/terraform_{version}_{os}_{arch}.zip",
+		Checksums: "https://releases.hashicorp.com/terraform/{version}/terraform_{version}_SHA256SUMS",
+	},
+	BinaryOpenTofu: {
+		Index:     "https://get.opentofu.org/tofu/api.json",
+		Archive:   "https://github.com/opentofu/opentofu/releases/download/v{version}/tofu_{version}_{os}_{arch}.zip",
+		Checksums: "https://github.com/opentofu/opentofu/releases/download/v{version}/tofu_{version}_SHA256SUMS",
+	},
+}
+
+func (s releaseSource) url(pattern string, v *version.Version) string {
+	return strings.NewReplacer(
+		"{version}", v.String(),
+		"{os}", runtime.GOOS,
+		"{arch}", runtime.GOARCH,
+	).Replace(pattern)
+}
+
+type BinaryOptions struct {
+	Binary  string
+	Version string
+	Install bool
+}
+
+// ResolveBinary returns the binary to run terraform commands with. If a version constraint is set the binary on the
+// PATH is only used when its version matches, otherwise a matching version from the cache directory is used. When
+// install is set, a matching version is downloaded if none is available yet.
+func ResolveBinary(ctx context.Context, opts BinaryOptions) (string, error) {
+	name := opts.Binary
+	if name == "" {
+		name = BinaryTerraform
+	}
+	if _, ok := releaseSources[name]; !ok {
+		return "", fmt.Errorf("unsupported terraform binary %s, expected %s or %s", name, BinaryTerraform, BinaryOpenTofu)
+	}
+
+	if opts.Version == "" {
+		return name, nil
+	}
+
+	constraints, err := ParseVersionConstraint(opts.Version)
+	if err != nil {
+		return "", err
+	}
+
+	found := "not found"
+	if p, err := exec.LookPath(name); err == nil {
+		v, err := binaryVersion(ctx, p)
+		if err != nil {
+			return "", err
+		}
+		if constraints.Check(v) {
+			log.Debug().Msgf("Using %s %s from %s", name, v, p)
+			return p, nil
+		}
+		found = v.String()
+	}
+
+	if p := installedBinary(name, constraints); p != "" {
+		log.Debug().Msgf("Using %s from %s", name, p)
+		return p, nil
+	}
+
+	if !opts.Install {
+		return "", fmt.Errorf(
+			"%s version %s is required, but the version on the PATH is %s. Install a matching version or set "+
+				"`install: true` to download it", name, opts.Version, found)
+	}
+
+	return installBinary(name, constraints)
+}
+
+// ParseVersionConstraint parses a version constraint. Besides the constraints supported by terraform, versions
+// with a wildcard like `1.7.x` are supported.
+func ParseVersionConstraint(value string) (version.Constraints, error) {
+	parts := strings.Split(strings.TrimSpace(value), ".")
+	last := parts[len(parts)-1]
+	if len(parts) > 1 && (last == "x" || last == "*") {
+		lower, err := version.NewVersion(strings.Join(parts[:len(parts)-1], "."))
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %s: %w", value, err)
+		}
+
+		// The upper bound is the next version of the last segment before the wildcard
+		segments := lower.Segments()[:len(parts)-1]
+		segments[len(segments)-1]++
+		upper := make([]string, len(segments))
+		for i, segment := range segments {
+			upper[i] = strconv.Itoa(segment)
+		}
+		value = fmt.Sprintf(">= %s, < %s", lower, strings.Join(upper, "."))
+	}
+
+	constraints, err := version.NewConstraint(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version constraint %s: %w", value, err)
+	}
+	return constraints, nil
+}
+
+func binaryVersion(ctx context.Context, binary string) (*version.Version, error) {
+	out, err := exec.CommandContext(ctx, binary, "version", "-json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version of %s: %w", binary, err)
+	}
+
+	// OpenTofu reports its version in the same field as terraform
+	data := struct {
+		Version string `json:"terraform_version"`
+	}{}
+	if err := json.Unmarshal(out, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse version of %s: %w", binary, err)
+	}
+	return version.NewVersion(data.Version)
+}
+
+func binaryCacheDir(name string) string {
+	return path.Join(xdg.CacheHome, "mach-composer", "bin", name)
+}
+
+func binaryPath(name string, v *version.Version) string {
+	filename := name
+	if runtime.GOOS == "windows" {
+		filename += ".exe"
+	}
+	return path.Join(binaryCacheDir(name), v.String(), filename)
+}
+
+// installedBinary returns the path of the highest version in the cache directory that matches the constraints
+func installedBinary(name string, constraints version.Constraints) string {
+	entries, err := os.ReadDir(binaryCacheDir(name))
+	if err != nil {
+		return ""
+	}
+
+	var versions []*version.Version
+	for _, e := range entries {
+		v, err := version.NewVersion(e.Name())
+		if err != nil || !constraints.Check(v) {
+			continue
+		}
+		if _, err := os.Stat(binaryPath(name, v)); err != nil {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	if len(versions) == 0 {
+		return ""
+	}
+
+	sort.Sort(sort.Reverse(version.Collection(versions)))
+	return binaryPath(name, versions[0])
+}
+
+func installBinary(name string, constraints version.Constraints) (string, error) {
+	source := releaseSources[name]
+
+	versions, err := fetchReleaseVersions(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s releases: %w", name, err)
+	}
+
+	var v *version.Version
+	for _, candidate := range versions {
+		if candidate.Prerelease() == "" && constraints.Check(candidate) {
+			v = candidate
+			break
+		}
+	}
+	if v == nil {
+		return "", fmt.Errorf("no %s release matches version %s", name, constraints)
+	}
+
+	log.Info().Msgf("Downloading %s %s...", name, v)
+
+	client := getter.Client{
+		DisableSymlinks: true,
+		Src:             fmt.Sprintf("%s?checksum=file:%s", source.url(source.Archive, v), source.url(source.Checksums, v)),
+		Dst:             path.Dir(binaryPath(name, v)),
+		Mode:            getter.ClientModeDir,
+	}
+	if err := client.Get(); err != nil {
+		return "", fmt.Errorf("failed to download %s %s: %w", name, v, err)
+	}
+
+	p := binaryPath(name, v)
+	if err := os.Chmod(p, 0755); err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
+// fetchReleaseVersions returns the released versions, ordered from high to low. Terraform lists the versions as keys
+// of an object, OpenTofu as a list of objects.
+func fetchReleaseVersions(source releaseSource) ([]*version.Version, error) {
+	client := retryablehttp.NewClient()
+	client.Logger = nil
+
+	r, err := client.Get(source.Index)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", r.StatusCode)
+	}
+
+	index := struct {
+		Versions json.RawMessage `json:"versions"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&index); err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	keyed := map[string]json.RawMessage{}
+	listed := []struct {
+		ID string `json:"id"`
+	}{}
+	if err := json.Unmarshal(index.Versions, &keyed); err == nil {
+		for id := range keyed {
+			ids = append(ids, id)
+		}
+	} else if err := json.Unmarshal(index.Versions, &listed); err == nil {
+		for _, item := range listed {
+			ids = append(ids, item.ID)
+		}
+	} else {
+		return nil, fmt.Errorf("unexpected release index format")
+	}
+
+	var versions []*version.Version
+	for _, id := range ids {
+		if v, err := version.NewVersion(id); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	sort.Sort(sort.Reverse(version.Collection(versions)))
+	return versions, nil
+}
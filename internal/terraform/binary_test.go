@@ -0,0 +1,165 @@
+package terraform
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/hashicorp/go-version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupBinaryDirs(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("PATH", t.TempDir())
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+}
+
+// fakeBinary returns a shell script that reports the given version like terraform does
+func fakeBinary(v string) []byte {
+	return []byte(fmt.Sprintf("#!/bin/sh\necho '{\"terraform_version\": \"%s\"}'\n", v))
+}
+
+func newTestReleaseServer(t *testing.T, name string, versions ...string) {
+	archives := map[string][]byte{}
+	for _, v := range versions {
+		buf := &bytes.Buffer{}
+		zw := zip.NewWriter(buf)
+		f, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = f.Write(fakeBinary(v))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		archives[v] = buf.Bytes()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/index.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"versions": [`)
+		for i, v := range versions {
+			if i > 0 {
+				_, _ = fmt.Fprint(w, ",")
+			}
+			_, _ = fmt.Fprintf(w, `{"id": "%s"}`, v)
+		}
+		_, _ = fmt.Fprint(w, `]}`)
+	})
+	for v, data := range archives {
+		filename := fmt.Sprintf("%s_%s_%s_%s.zip", name, v, runtime.GOOS, runtime.GOARCH)
+		sum := sha256.Sum256(data)
+		data := data
+
+		mux.HandleFunc("/"+v+"/"+filename, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(data)
+		})
+		mux.HandleFunc("/"+v+"/SHA256SUMS", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, "%s  %s\n", hex.EncodeToString(sum[:]), filename)
+		})
+	}
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	original := releaseSources[name]
+	releaseSources[name] = releaseSource{
+		Index:     server.URL + "/index.json",
+		Archive:   server.URL + "/{version}/" + name + "_{version}_{os}_{arch}.zip",
+		Checksums: server.URL + "/{version}/SHA256SUMS",
+	}
+	t.Cleanup(func() { releaseSources[name] = original })
+}
+
+func TestParseVersionConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		matches    []string
+		rejects    []string
+	}{
+		{"1.7.x", []string{"1.7.0", "1.7.5"}, []string{"1.6.9", "1.8.0"}},
+		{"1.x", []string{"1.0.0", "1.9.2"}, []string{"0.15.5", "2.0.0"}},
+		{">= 1.5, < 2.0", []string{"1.5.0", "1.9.0"}, []string{"1.4.0", "2.0.0"}},
+		{"1.6.2", []string{"1.6.2"}, []string{"1.6.3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			c, err := ParseVersionConstraint(tt.constraint)
+			require.NoError(t, err)
+			for _, v := range tt.matches {
+				assert.True(t, c.Check(mustVersion(t, v)), v)
+			}
+			for _, v := range tt.rejects {
+				assert.False(t, c.Check(mustVersion(t, v)), v)
+			}
+		})
+	}
+
+	_, err := ParseVersionConstraint("a.x")
+	assert.Error(t, err)
+}
+
+func TestResolveBinaryWithoutVersion(t *testing.T) {
+	p, err := ResolveBinary(context.Background(), BinaryOptions{Binary: BinaryOpenTofu})
+	require.NoError(t, err)
+	assert.Equal(t, "tofu", p)
+
+	_, err = ResolveBinary(context.Background(), BinaryOptions{Binary: "unknown"})
+	assert.Error(t, err)
+}
+
+func TestResolveBinaryFromPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as binary")
+	}
+	setupBinaryDirs(t)
+
+	binDir := os.Getenv("PATH")
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "terraform"), fakeBinary("1.7.5"), 0755))
+
+	p, err := ResolveBinary(context.Background(), BinaryOptions{Version: "1.7.x"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(binDir, "terraform"), p)
+
+	_, err = ResolveBinary(context.Background(), BinaryOptions{Version: "1.8.x"})
+	assert.ErrorContains(t, err, "the version on the PATH is 1.7.5")
+}
+
+func TestResolveBinaryInstall(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as binary")
+	}
+	setupBinaryDirs(t)
+	newTestReleaseServer(t, BinaryOpenTofu, "1.6.2", "1.7.1", "1.7.3", "1.8.0-beta1")
+
+	opts := BinaryOptions{Binary: BinaryOpenTofu, Version: "1.7.x", Install: true}
+	p, err := ResolveBinary(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(xdg.CacheHome, "mach-composer", "bin", "tofu", "1.7.3", "tofu"), p)
+
+	v, err := binaryVersion(context.Background(), p)
+	require.NoError(t, err)
+	assert.Equal(t, "1.7.3", v.String())
+
+	// The installed version is used without installing it again
+	opts.Install = false
+	cached, err := ResolveBinary(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, p, cached)
+}
+
+func mustVersion(t *testing.T, v string) *version.Version {
+	result, err := version.NewVersion(v)
+	require.NoError(t, err)
+	return result
+}
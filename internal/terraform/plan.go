@@ -14,6 +14,12 @@ func PlanWithNoLock() PlanOption {
 	}
 }
 
+func PlanWithDestroy() PlanOption {
+	return func(args []string) []string {
+		return append(args, "-destroy")
+	}
+}
+
 func PlanWithJson() PlanOption {
 	return func(args []string) []string {
 		return append(args, "-json")
@@ -21,13 +27,18 @@ func PlanWithJson() PlanOption {
 }
 
 func Plan(ctx context.Context, path string, opts ...PlanOption) (string, error) {
+	return PlanTo(ctx, path, PlanFile, opts...)
+}
+
+// PlanTo stores the plan in the given file, relative to the path, instead of the default plan file
+func PlanTo(ctx context.Context, path, filename string, opts ...PlanOption) (string, error) {
 	args := []string{"plan"}
 
 	for _, opt := range opts {
 		args = opt(args)
 	}
 
-	args = append(args, fmt.Sprintf("-out=%s", PlanFile))
+	args = append(args, fmt.Sprintf("-out=%s", filename))
 
 	return utils.RunTerraform(ctx, path, args...)
 }
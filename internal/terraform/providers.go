@@ -0,0 +1,18 @@
+package terraform
+
+import (
+	"context"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+)
+
+// ProvidersLock records the checksums of the required providers for the given platforms in the dependency lock file.
+// Without platforms only the current platform is recorded.
+func ProvidersLock(ctx context.Context, path string, platforms ...string) (string, error) {
+	args := []string{"providers", "lock"}
+
+	for _, platform := range platforms {
+		args = append(args, "-platform="+platform)
+	}
+
+	return utils.RunTerraform(ctx, path, args...)
+}
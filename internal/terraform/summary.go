@@ -0,0 +1,162 @@
+package terraform
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+)
+
+type Action string
+
+const (
+	ActionCreate  Action = "create"
+	ActionUpdate  Action = "update"
+	ActionDelete  Action = "delete"
+	ActionReplace Action = "replace"
+)
+
+// ResourceChange is a single planned change to a resource
+type ResourceChange struct {
+	Address string `json:"address"`
+	Type    string `json:"type"`
+	Action  Action `json:"action"`
+}
+
+// PlanSummary contains the resource changes of a terraform plan
+type PlanSummary struct {
+	Changes []ResourceChange `json:"changes"`
+	// Digest is the sha256 hash of the complete plan, apart from the time it was created. Planning the same changes
+	// to the same state results in the same digest.
+	Digest string `json:"-"`
+}
+
+type planJSON struct {
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Type    string `json:"type"`
+		Change  struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// ParsePlanSummary reads the resource changes from the output of `terraform show -json`. Resources without changes
+// and data sources that are read are left out.
+func ParsePlanSummary(data []byte) (*PlanSummary, error) {
+	plan := planJSON{}
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+
+	digest, err := planDigest(data)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &PlanSummary{Digest: digest}
+	for _, rc := range plan.ResourceChanges {
+		var action Action
+		switch strings.Join(rc.Change.Actions, ",") {
+		case "create":
+			action = ActionCreate
+		case "update":
+			action = ActionUpdate
+		case "delete":
+			action = ActionDelete
+		case "delete,create", "create,delete":
+			action = ActionReplace
+		default:
+			continue
+		}
+		summary.Changes = append(summary.Changes, ResourceChange{
+			Address: rc.Address,
+			Type:    rc.Type,
+			Action:  action,
+		})
+	}
+	return summary, nil
+}
+
+// planDigest hashes the plan without its timestamp. The plan is encoded again, which sorts the keys of all objects.
+func planDigest(data []byte) (string, error) {
+	plan := map[string]any{}
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return "", fmt.Errorf("failed to parse plan: %w", err)
+	}
+	delete(plan, "timestamp")
+
+	normalized, err := json.Marshal(plan)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(normalized)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// ShowPlanSummary returns the summary of the plan stored in the given file, relative to the path
+func ShowPlanSummary(ctx context.Context, path, filename string) (*PlanSummary, error) {
+	if _, err := os.Stat(filepath.Join(path, filename)); err != nil {
+		return nil, fmt.Errorf("no plan found for path %s", path)
+	}
+
+	// The plan is only used internally, so it should not be streamed
+	out, err := utils.RunTerraform(utils.ContextWithOutputWriter(ctx, nil), path, "show", "-json", filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePlanSummary([]byte(out))
+}
+
+// Counts returns the number of resources to add, change and destroy. Replaced resources are both added and destroyed,
+// like terraform reports them.
+func (s *PlanSummary) Counts() (add, change, destroy int) {
+	for _, c := range s.Changes {
+		switch c.Action {
+		case ActionCreate:
+			add++
+		case ActionUpdate:
+			change++
+		case ActionDelete:
+			destroy++
+		case ActionReplace:
+			add++
+			destroy++
+		}
+	}
+	return add, change, destroy
+}
+
+func (s *PlanSummary) HasChanges() bool {
+	return len(s.Changes) > 0
+}
+
+func (s *PlanSummary) String() string {
+	var b strings.Builder
+	add, change, destroy := s.Counts()
+	b.WriteString(fmt.Sprintf("Plan: %d to add, %d to change, %d to destroy.", add, change, destroy))
+	for _, c := range s.Changes {
+		b.WriteString(fmt.Sprintf("\n  %3s %s", actionSymbol(c.Action), c.Address))
+	}
+	return b.String()
+}
+
+func actionSymbol(a Action) string {
+	switch a {
+	case ActionCreate:
+		return "+"
+	case ActionUpdate:
+		return "~"
+	case ActionDelete:
+		return "-"
+	case ActionReplace:
+		return "-/+"
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlanSummary(t *testing.T) {
+	summary, err := ParsePlanSummary([]byte(`{
+		"format_version": "1.2",
+		"resource_changes": [
+			{"address": "aws_s3_bucket.new", "type": "aws_s3_bucket", "change": {"actions": ["create"]}},
+			{"address": "aws_s3_bucket.same", "change": {"actions": ["no-op"]}},
+			{"address": "data.aws_caller_identity.current", "change": {"actions": ["read"]}},
+			{"address": "aws_s3_bucket.changed", "change": {"actions": ["update"]}},
+			{"address": "aws_s3_bucket.replaced", "change": {"actions": ["delete", "create"]}},
+			{"address": "aws_s3_bucket.removed", "change": {"actions": ["delete"]}}
+		]
+	}`))
+	require.NoError(t, err)
+
+	assert.True(t, summary.HasChanges())
+	assert.Equal(t, "aws_s3_bucket", summary.Changes[0].Type)
+	add, change, destroy := summary.Counts()
+	assert.Equal(t, 2, add)
+	assert.Equal(t, 1, change)
+	assert.Equal(t, 2, destroy)
+
+	assert.Equal(t, `Plan: 2 to add, 1 to change, 2 to destroy.
+    + aws_s3_bucket.new
+    ~ aws_s3_bucket.changed
+  -/+ aws_s3_bucket.replaced
+    - aws_s3_bucket.removed`, summary.String())
+}
+
+func TestParsePlanSummaryNoChanges(t *testing.T) {
+	summary, err := ParsePlanSummary([]byte(`{"format_version": "1.2"}`))
+	require.NoError(t, err)
+	assert.False(t, summary.HasChanges())
+}
+
+func TestParsePlanSummaryDigest(t *testing.T) {
+	summary, err := ParsePlanSummary([]byte(`{"format_version": "1.2", "timestamp": "2024-01-01T12:00:00Z",
+		"prior_state": {"values": {"outputs": {"url": {"value": "a"}}}}}`))
+	require.NoError(t, err)
+	assert.Len(t, summary.Digest, 64)
+
+	// The digest does not depend on when the plan was made, or on the order of the keys
+	same, err := ParsePlanSummary([]byte(`{"timestamp": "2024-01-02T12:00:00Z", "format_version": "1.2",
+		"prior_state": {"values": {"outputs": {"url": {"value": "a"}}}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, summary.Digest, same.Digest)
+
+	// Any other difference results in another digest, even without resource changes
+	other, err := ParsePlanSummary([]byte(`{"format_version": "1.2", "timestamp": "2024-01-01T12:00:00Z",
+		"prior_state": {"values": {"outputs": {"url": {"value": "b"}}}}}`))
+	require.NoError(t, err)
+	assert.NotEqual(t, summary.Digest, other.Digest)
+}
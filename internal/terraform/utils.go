@@ -1,6 +1,7 @@
 package terraform
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 )
@@ -14,3 +15,19 @@ func hasTerraformPlan(path string) (string, error) {
 	}
 	return "", nil
 }
+
+// HasPlan returns whether a plan is stored for the given path
+func HasPlan(path string) bool {
+	filename, _ := hasTerraformPlan(path)
+	return filename != ""
+}
+
+// RemovePlan removes the plan stored in the given file, relative to the path. A plan can only be applied once, so it
+// should be removed once it is applied.
+func RemovePlan(path, filename string) error {
+	err := os.Remove(filepath.Join(path, filename))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
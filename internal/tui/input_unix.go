@@ -0,0 +1,28 @@
+//go:build !windows
+
+package tui
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// waitForInput returns whether input can be read from the file within the timeout
+func waitForInput(f *os.File, timeout time.Duration) (bool, error) {
+	fd := int(f.Fd())
+	fds := &unix.FdSet{}
+	fds.Set(fd)
+	tv := unix.NsecToTimeval(timeout.Nanoseconds())
+
+	n, err := unix.Select(fd+1, fds, nil, nil, &tv)
+	if errors.Is(err, unix.EINTR) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
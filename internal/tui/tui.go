@@ -0,0 +1,460 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/term"
+
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
+	"github.com/mach-composer/mach-composer-cli/internal/runner"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+)
+
+type nodeState string
+
+const (
+	statePending   nodeState = "pending"
+	stateWaiting   nodeState = "waiting on parents"
+	stateRunning   nodeState = "running"
+	stateSkipped   nodeState = "skipped"
+	stateSucceeded nodeState = "succeeded"
+	stateFailed    nodeState = "failed"
+)
+
+type node struct {
+	identifier string
+	batch      int
+	state      nodeState
+	reason     string
+	phase      runner.Phase
+	retries    int
+	started    time.Time
+	finished   time.Time
+	lines      []string
+}
+
+func (n *node) elapsed(now time.Time) time.Duration {
+	switch {
+	case n.started.IsZero():
+		return 0
+	case n.finished.IsZero():
+		return now.Sub(n.started).Truncate(time.Second)
+	default:
+		return n.finished.Sub(n.started).Truncate(time.Second)
+	}
+}
+
+// UI is an interactive terminal UI that shows the progress of every node of a graph run. It is driven by the events
+// emitted by the runner.GraphRunner it is subscribed to
+type UI struct {
+	in  *os.File
+	out *os.File
+
+	mu       sync.Mutex
+	nodes    []*node
+	index    map[string]*node
+	batch    int
+	batches  int
+	selected int
+	expanded bool
+}
+
+func New() *UI {
+	return &UI{
+		in:    os.Stdin,
+		out:   os.Stdout,
+		index: make(map[string]*node),
+	}
+}
+
+func (u *UI) HandleEvent(e runner.Event) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	switch e.Type {
+	case runner.EventNodeQueued:
+		n := &node{identifier: e.Node, batch: e.Batch, state: stateWaiting}
+		if _, ok := u.index[e.Node]; !ok {
+			u.nodes = append(u.nodes, n)
+		} else {
+			for i := range u.nodes {
+				if u.nodes[i].identifier == e.Node {
+					u.nodes[i] = n
+				}
+			}
+		}
+		u.index[e.Node] = n
+		if e.Batch+1 > u.batches {
+			u.batches = e.Batch + 1
+		}
+	case runner.EventBatchStarted:
+		u.batch = e.Batch
+		for _, n := range u.nodes {
+			if n.batch == e.Batch && n.state == stateWaiting {
+				n.state = statePending
+			}
+		}
+	}
+
+	n, ok := u.index[e.Node]
+	if !ok {
+		return
+	}
+
+	switch e.Type {
+	case runner.EventNodeStarted:
+		n.state = stateRunning
+		n.started = e.Time
+	case runner.EventNodeSkipped:
+		n.reason = e.Reason
+	case runner.EventPhaseChanged:
+		n.phase = e.Phase
+	case runner.EventNodeRetried:
+		n.retries++
+	case runner.EventNodeOutput:
+		n.lines = append(n.lines, e.Line)
+	case runner.EventNodeFinished:
+		if !n.started.IsZero() {
+			n.finished = e.Time
+		}
+		switch e.Result {
+		case runner.ResultSkipped:
+			n.state = stateSkipped
+		case runner.ResultFailed:
+			n.state = stateFailed
+			n.reason = e.Error
+		default:
+			n.state = stateSucceeded
+		}
+	}
+}
+
+// Run shows the UI until fn returns. Log messages are held back while the UI is shown and written once it is closed,
+// followed by a summary of the run. If the terminal does not support the UI fn is run as-is. When ctx is cancelled the
+// terminal is given back right away, while fn is shutting down.
+func (u *UI) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	fd := int(u.in.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		log.Debug().Err(err).Msg("Terminal does not support the interactive UI")
+		return fn(ctx)
+	}
+
+	// Switch to the alternate screen and hide the cursor
+	_, _ = fmt.Fprint(u.out, "\x1b[?1049h\x1b[?25l")
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	keys := make(chan string)
+	go func() {
+		defer close(stopped)
+		u.readKeys(stop, keys)
+	}()
+
+	// closeUI stops reading keys and restores the terminal. Waiting for the key reader ensures it does not take any
+	// input meant for prompts shown after the UI is closed
+	var closeOnce sync.Once
+	closeUI := func() {
+		closeOnce.Do(func() {
+			close(stop)
+			<-stopped
+			_, _ = fmt.Fprint(u.out, "\x1b[?25h\x1b[?1049l")
+			_ = term.Restore(fd, state)
+		})
+	}
+	defer closeUI()
+
+	logs := &logBuffer{}
+	logWriter := cli.LogWriterFromContext(ctx)
+	original := log.Logger
+	logger := log.Output(logs)
+	log.Logger = logger
+	defer func() { log.Logger = original }()
+
+	ctx = logger.WithContext(ctx)
+	ctx = cli.ContextWithLogWriter(ctx, logs)
+	// The terminal is used by the UI, so commands cannot prompt for input
+	ctx = utils.ContextWithNonInteractive(ctx)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	cancelled := ctx.Done()
+	active := true
+	for {
+		if active {
+			u.render()
+		}
+
+		select {
+		case err = <-done:
+			closeUI()
+			log.Logger = original
+
+			if flushErr := logs.flush(logWriter); flushErr != nil && err == nil {
+				err = flushErr
+			}
+			u.printSummary(u.out)
+			return err
+		case <-cancelled:
+			closeUI()
+			active, cancelled = false, nil
+		case key := <-keys:
+			u.handleKey(key)
+		case <-ticker.C:
+		}
+	}
+}
+
+// readKeys sends the pressed keys until stop is closed. Input is only read once it is available, so the reader can be
+// stopped without waiting for another key press
+func (u *UI) readKeys(stop <-chan struct{}, keys chan<- string) {
+	buf := make([]byte, 8)
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		ready, err := waitForInput(u.in, 100*time.Millisecond)
+		if err != nil {
+			return
+		}
+		if !ready {
+			continue
+		}
+
+		n, err := u.in.Read(buf)
+		if err != nil {
+			return
+		}
+		select {
+		case keys <- string(buf[:n]):
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (u *UI) handleKey(key string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	switch key {
+	case "\x1b[A", "k":
+		if u.selected > 0 {
+			u.selected--
+		}
+	case "\x1b[B", "j":
+		if u.selected < len(u.nodes)-1 {
+			u.selected++
+		}
+	case "\r", " ":
+		u.expanded = !u.expanded
+	case "\x1b":
+		u.expanded = false
+	case "\x03":
+		// The terminal is in raw mode, so ctrl+c does not send an interrupt by itself
+		if p, err := os.FindProcess(os.Getpid()); err == nil {
+			_ = p.Signal(os.Interrupt)
+		}
+	}
+}
+
+func (u *UI) render() {
+	width, height, err := term.GetSize(int(u.out.Fd()))
+	if err != nil {
+		width, height = 80, 24
+	}
+
+	lines := u.view(width, height, time.Now())
+
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for _, line := range lines {
+		b.WriteString(line)
+		b.WriteString("\x1b[K\r\n")
+	}
+	b.WriteString("\x1b[J")
+	_, _ = fmt.Fprint(u.out, b.String())
+}
+
+// view returns the lines of the screen for the given terminal size
+func (u *UI) view(width, height int, now time.Time) []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	bold := color.New(color.Bold).SprintFunc()
+	faint := color.New(color.Faint).SprintFunc()
+
+	lines := []string{
+		bold(fmt.Sprintf("Batch %d/%d", u.batch+1, u.batches)) +
+			faint("  ↑/↓ select  enter show/hide log  ctrl+c cancel"),
+		"",
+	}
+
+	nameWidth := 0
+	for _, n := range u.nodes {
+		nameWidth = max(nameWidth, len(n.identifier))
+	}
+
+	for i, n := range u.nodes {
+		cursor := "  "
+		if i == u.selected {
+			cursor = "> "
+		}
+
+		status := string(n.state)
+		switch {
+		case n.state == stateSkipped && n.reason != "":
+			status = fmt.Sprintf("%s (%s)", n.state, n.reason)
+		case n.state == stateRunning && n.retries > 0:
+			status = fmt.Sprintf("%s (%s, retry %d)", n.state, n.phase, n.retries)
+		case n.state == stateRunning && n.phase != "":
+			status = fmt.Sprintf("%s (%s)", n.state, n.phase)
+		}
+
+		elapsed := ""
+		if d := n.elapsed(now); d > 0 || n.state == stateRunning {
+			elapsed = d.String()
+		}
+
+		last := ""
+		if len(n.lines) > 0 {
+			last = strings.TrimSpace(n.lines[len(n.lines)-1])
+		}
+
+		line := fmt.Sprintf("%s%s %-*s  %-28s %7s  ", cursor, stateSymbol(n.state), nameWidth, n.identifier, status, elapsed)
+		lines = append(lines, line+faint(last))
+	}
+
+	if u.expanded && u.selected < len(u.nodes) {
+		n := u.nodes[u.selected]
+		lines = append(lines, "", bold(fmt.Sprintf("Output of %s", n.identifier)))
+
+		available := height - len(lines) - 1
+		output := n.lines
+		if available < 1 {
+			output = nil
+		} else if len(output) > available {
+			output = output[len(output)-available:]
+		}
+		for _, line := range output {
+			lines = append(lines, line)
+		}
+	}
+
+	// Leave the last row empty, as every line is terminated with a newline
+	if len(lines) > height-1 {
+		lines = lines[:height-1]
+	}
+	for i := range lines {
+		lines[i] = truncate(lines[i], width)
+	}
+	return lines
+}
+
+// printSummary writes the final state of every node
+func (u *UI) printSummary(w io.Writer) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	now := time.Now()
+	_, _ = fmt.Fprintln(w)
+	for _, n := range u.nodes {
+		line := fmt.Sprintf("%s %s %s", stateSymbol(n.state), n.identifier, n.state)
+		if d := n.elapsed(now); d > 0 {
+			line += fmt.Sprintf(" (%s)", d)
+		}
+		if n.retries > 0 {
+			line += fmt.Sprintf(" after %d retries", n.retries)
+		}
+		if n.reason != "" {
+			line += ": " + n.reason
+		}
+		_, _ = fmt.Fprintln(w, line)
+	}
+}
+
+func stateSymbol(s nodeState) string {
+	switch s {
+	case stateRunning:
+		return color.New(color.FgCyan).Sprint("●")
+	case stateSucceeded:
+		return color.New(color.FgGreen).Sprint("✓")
+	case stateFailed:
+		return color.New(color.FgRed).Sprint("✗")
+	case stateSkipped:
+		return color.New(color.Faint).Sprint("-")
+	default:
+		return color.New(color.Faint).Sprint("○")
+	}
+}
+
+// truncate cuts off a line at the given width. Escape sequences used for colors are not counted
+func truncate(line string, width int) string {
+	var b strings.Builder
+	visible := 0
+	escape, colored := false, false
+	for _, r := range line {
+		switch {
+		case r == '\x1b':
+			escape, colored = true, true
+		case escape:
+			if r == 'm' {
+				escape = false
+			}
+		default:
+			if visible >= width {
+				if colored {
+					b.WriteString("\x1b[0m")
+				}
+				return b.String()
+			}
+			visible++
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// logBuffer holds back log messages while the UI is shown
+type logBuffer struct {
+	mu       sync.Mutex
+	messages [][]byte
+}
+
+func (l *logBuffer) Write(p []byte) (n int, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.messages = append(l.messages, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func (l *logBuffer) flush(w io.Writer) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, p := range l.messages {
+		if _, err := w.Write(p); err != nil {
+			return err
+		}
+	}
+	l.messages = nil
+	return nil
+}
@@ -0,0 +1,111 @@
+package tui
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mach-composer/mach-composer-cli/internal/runner"
+)
+
+func TestUIHandleEvents(t *testing.T) {
+	color.NoColor = true
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	ui := New()
+	for _, e := range []runner.Event{
+		{Type: runner.EventNodeQueued, Batch: 0, Node: "site-1"},
+		{Type: runner.EventNodeQueued, Batch: 1, Node: "component-1"},
+		{Type: runner.EventNodeQueued, Batch: 1, Node: "component-2"},
+		{Type: runner.EventNodeQueued, Batch: 2, Node: "component-3"},
+		{Type: runner.EventBatchStarted, Batch: 0},
+		{Type: runner.EventNodeSkipped, Batch: 0, Node: "site-1", Reason: "no changes"},
+		{Type: runner.EventNodeFinished, Batch: 0, Node: "site-1", Result: runner.ResultSkipped},
+		{Type: runner.EventBatchStarted, Batch: 1},
+		{Type: runner.EventNodeStarted, Batch: 1, Node: "component-1", Time: start},
+		{Type: runner.EventPhaseChanged, Batch: 1, Node: "component-1", Phase: runner.PhaseApply},
+		{Type: runner.EventNodeOutput, Batch: 1, Node: "component-1", Line: "Refreshing state..."},
+		{Type: runner.EventNodeStarted, Batch: 1, Node: "component-2", Time: start},
+		{Type: runner.EventNodeFinished, Batch: 1, Node: "component-2", Time: start.Add(5 * time.Second), Result: runner.ResultFailed, Error: "boom"},
+	} {
+		ui.HandleEvent(e)
+	}
+
+	assert.Equal(t, stateSkipped, ui.index["site-1"].state)
+	assert.Equal(t, stateRunning, ui.index["component-1"].state)
+	assert.Equal(t, stateFailed, ui.index["component-2"].state)
+	assert.Equal(t, stateWaiting, ui.index["component-3"].state)
+
+	lines := ui.view(120, 24, start.Add(10*time.Second))
+	require.Len(t, lines, 6)
+	assert.Equal(t, "Batch 2/3  ↑/↓ select  enter show/hide log  ctrl+c cancel", lines[0])
+	assert.Contains(t, lines[2], "skipped (no changes)")
+	assert.Contains(t, lines[3], "running (apply)")
+	assert.Contains(t, lines[3], "10s")
+	assert.Contains(t, lines[3], "Refreshing state...")
+	assert.Contains(t, lines[4], "failed")
+	assert.Contains(t, lines[4], "5s")
+	assert.Contains(t, lines[5], "waiting on parents")
+}
+
+func TestUIExpandedView(t *testing.T) {
+	color.NoColor = true
+
+	ui := New()
+	ui.HandleEvent(runner.Event{Type: runner.EventNodeQueued, Node: "component-1"})
+	ui.HandleEvent(runner.Event{Type: runner.EventBatchStarted})
+	ui.HandleEvent(runner.Event{Type: runner.EventNodeStarted, Node: "component-1", Time: time.Now()})
+	for i := 0; i < 20; i++ {
+		ui.HandleEvent(runner.Event{Type: runner.EventNodeOutput, Node: "component-1", Line: strings.Repeat("x", i)})
+	}
+
+	ui.handleKey("\r")
+	lines := ui.view(10, 10, time.Now())
+	require.Len(t, lines, 9)
+	assert.Equal(t, "Output of ", lines[4])
+	// Only the last lines of output that fit on the screen are shown, cut off at the terminal width
+	assert.Equal(t, strings.Repeat("x", 10), lines[8])
+
+	ui.handleKey("\x1b")
+	assert.Len(t, ui.view(10, 10, time.Now()), 3)
+}
+
+func TestUIReadKeysStops(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	ui := &UI{in: r}
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	keys := make(chan string)
+	go func() {
+		defer close(stopped)
+		ui.readKeys(stop, keys)
+	}()
+
+	_, err = w.WriteString("j")
+	require.NoError(t, err)
+	assert.Equal(t, "j", <-keys)
+
+	close(stop)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("key reader did not stop")
+	}
+
+	// Input written after the reader stopped is left for the next reader
+	_, err = w.WriteString("y\n")
+	require.NoError(t, err)
+	buf := make([]byte, 2)
+	_, err = r.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "y\n", string(buf))
+}
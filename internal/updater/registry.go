@@ -0,0 +1,161 @@
+package updater
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/rs/zerolog/log"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+)
+
+// registryClient is used for all registry requests. Tests replace it with the client of a local registry.
+var registryClient = &http.Client{Timeout: 10 * time.Second}
+
+type registryModuleVersions struct {
+	Modules []struct {
+		Versions []struct {
+			Version string `json:"version"`
+		} `json:"versions"`
+	} `json:"modules"`
+}
+
+// getLastVersionRegistry finds the latest version of a registry module. A version constraint that already allows the
+// latest version is kept as is.
+func getLastVersionRegistry(ctx context.Context, c *config.ComponentConfig) (*ChangeSet, error) {
+	source, err := c.Source.Registry()
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := fetchRegistryVersions(ctx, source)
+	if err != nil {
+		return nil, &UpdateError{
+			msg:       fmt.Sprintf("failed to fetch versions of %s: %s", c.Name, err),
+			component: c.Name,
+			source:    string(c.Source),
+		}
+	}
+
+	cs := &ChangeSet{
+		Changes:     []CommitData{},
+		Component:   c,
+		LastVersion: c.Version,
+	}
+	if len(versions) == 0 {
+		log.Ctx(ctx).Warn().Msgf("No versions found for %s", c.Name)
+		return cs, nil
+	}
+
+	latest := versions[len(versions)-1]
+	if current, err := version.NewVersion(c.Version); err == nil {
+		if !current.Equal(latest) {
+			cs.LastVersion = latest.Original()
+		}
+		return cs, nil
+	}
+
+	if constraints, err := version.NewConstraint(c.Version); err == nil && constraints.Check(latest) {
+		return cs, nil
+	}
+	cs.LastVersion = latest.Original()
+	return cs, nil
+}
+
+// fetchRegistryVersions returns the released versions of the module, ordered from old to new. Pre-releases are
+// ignored.
+func fetchRegistryVersions(ctx context.Context, source *config.RegistrySource) ([]*version.Version, error) {
+	base, err := discoverModulesURL(ctx, source.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err := base.Parse(fmt.Sprintf("%s/%s/%s/versions",
+		url.PathEscape(source.Namespace), url.PathEscape(source.Name), url.PathEscape(source.Provider)))
+	if err != nil {
+		return nil, err
+	}
+
+	response := registryModuleVersions{}
+	if err := registryGet(ctx, source.Host, endpoint, &response); err != nil {
+		return nil, err
+	}
+
+	var versions []*version.Version
+	for _, m := range response.Modules {
+		for _, v := range m.Versions {
+			parsed, err := version.NewVersion(v.Version)
+			if err != nil {
+				log.Ctx(ctx).Debug().Msgf("Ignoring invalid version %s of %s/%s/%s", v.Version, source.Namespace,
+					source.Name, source.Provider)
+				continue
+			}
+			if parsed.Prerelease() != "" {
+				continue
+			}
+			versions = append(versions, parsed)
+		}
+	}
+	sort.Sort(version.Collection(versions))
+	return versions, nil
+}
+
+// discoverModulesURL uses the service discovery of the registry host to find the url of the modules api
+func discoverModulesURL(ctx context.Context, host string) (*url.URL, error) {
+	discovery := &url.URL{Scheme: "https", Host: host, Path: "/.well-known/terraform.json"}
+
+	services := map[string]any{}
+	if err := registryGet(ctx, host, discovery, &services); err != nil {
+		return nil, err
+	}
+
+	modules, ok := services["modules.v1"].(string)
+	if !ok {
+		return nil, fmt.Errorf("registry %s does not provide modules", host)
+	}
+	if !strings.HasSuffix(modules, "/") {
+		modules += "/"
+	}
+	return discovery.Parse(modules)
+}
+
+// registryGet decodes the json response of the url. The token of the host is taken from the `TF_TOKEN_<host>`
+// environment variable, like terraform does.
+func registryGet(ctx context.Context, host string, u *url.URL, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if token := os.Getenv(registryTokenVariable(host)); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := registryClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, u)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", u, err)
+	}
+	return nil
+}
+
+// registryTokenVariable returns the name of the environment variable with the token of the host, where dots are
+// replaced by underscores and dashes by double underscores
+func registryTokenVariable(host string) string {
+	host, _, _ = strings.Cut(host, ":")
+	return "TF_TOKEN_" + strings.NewReplacer(".", "_", "-", "__").Replace(host)
+}
@@ -0,0 +1,81 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+)
+
+func newTestRegistry(t *testing.T) string {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/terraform.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"modules.v1": "/api/registry/v1/modules"}`)
+	})
+	mux.HandleFunc("/api/registry/v1/modules/example-corp/vpc/aws/versions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"modules": [{"versions": [
+			{"version": "1.2.0"}, {"version": "1.10.0"}, {"version": "2.0.0-beta1"}, {"version": "1.9.1"}
+		]}]}`)
+	})
+
+	server := httptest.NewTLSServer(mux)
+	t.Cleanup(server.Close)
+
+	client := registryClient
+	registryClient = server.Client()
+	t.Cleanup(func() { registryClient = client })
+
+	host := strings.TrimPrefix(server.URL, "https://")
+	t.Setenv(registryTokenVariable(host), "secret")
+	return host
+}
+
+func TestGetLastVersionRegistry(t *testing.T) {
+	host := newTestRegistry(t)
+	source := config.Source(host + "/example-corp/vpc/aws//modules/subnets")
+
+	tests := []struct {
+		version  string
+		expected string
+	}{
+		{version: "1.2.0", expected: "1.10.0"},
+		{version: "1.10.0", expected: "1.10.0"},
+		{version: "~> 1.2", expected: "~> 1.2"},
+		{version: "~> 1.2.0", expected: "1.10.0"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.version, func(t *testing.T) {
+			c := &config.ComponentConfig{Name: "vpc", Source: source, Version: tc.version}
+			cs, err := getLastVersion(context.Background(), &PartialConfig{}, c, "")
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, cs.LastVersion)
+			assert.Equal(t, tc.version != tc.expected, cs.HasChanges())
+		})
+	}
+}
+
+func TestGetLastVersionRegistryUnknownModule(t *testing.T) {
+	host := newTestRegistry(t)
+
+	c := &config.ComponentConfig{Name: "dns", Source: config.Source(host + "/example-corp/dns/aws"), Version: "1.0.0"}
+	_, err := getLastVersion(context.Background(), &PartialConfig{}, c, "")
+	var updateErr *UpdateError
+	require.ErrorAs(t, err, &updateErr)
+	assert.Contains(t, err.Error(), "404 Not Found")
+}
+
+func TestRegistryTokenVariable(t *testing.T) {
+	assert.Equal(t, "TF_TOKEN_app_terraform_io", registryTokenVariable("app.terraform.io"))
+	assert.Equal(t, "TF_TOKEN_my__registry_example_com", registryTokenVariable("my-registry.example.com:8443"))
+}
@@ -153,6 +153,11 @@ func getLastVersion(ctx context.Context, cfg *PartialConfig, c *config.Component
 		c.Branch = "main"
 	}
 
+	// Registry modules are versioned by the registry, also when MACH Composer Cloud is used
+	if c.Source.IsType(config.SourceTypeRegistry) {
+		return getLastVersionRegistry(ctx, c)
+	}
+
 	if cfg.client != nil {
 		return getLastVersionCloud(ctx, cfg, c, origin)
 	}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
@@ -12,6 +13,63 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+const OutputWriterKey = "output-writer"
+
+// ContextWithOutputWriter returns a context in which the output of commands started with RunInteractive is also
+// written to w while the command is running
+func ContextWithOutputWriter(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, OutputWriterKey, w)
+}
+
+// OutputWriterFromContext returns the writer that command output is streamed to, or nil if output is not streamed
+func OutputWriterFromContext(ctx context.Context) io.Writer {
+	if v := ctx.Value(OutputWriterKey); v != nil {
+		return v.(io.Writer)
+	}
+
+	return nil
+}
+
+const NonInteractiveKey = "non-interactive"
+
+// ContextWithNonInteractive returns a context in which commands started with RunInteractive do not read from stdin
+func ContextWithNonInteractive(ctx context.Context) context.Context {
+	return context.WithValue(ctx, NonInteractiveKey, true)
+}
+
+// NonInteractiveFromContext returns whether commands are not allowed to read from stdin
+func NonInteractiveFromContext(ctx context.Context) bool {
+	if v := ctx.Value(NonInteractiveKey); v != nil {
+		return v.(bool)
+	}
+
+	return false
+}
+
+const EnvKey = "env"
+
+// ContextWithEnv returns a context in which commands started with RunInteractive get the given environment variables
+// in addition to the environment of mach-composer
+func ContextWithEnv(ctx context.Context, env map[string]string) context.Context {
+	merged := make(map[string]string)
+	for k, v := range EnvFromContext(ctx) {
+		merged[k] = v
+	}
+	for k, v := range env {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, EnvKey, merged)
+}
+
+// EnvFromContext returns the environment variables that are added to commands started with RunInteractive
+func EnvFromContext(ctx context.Context) map[string]string {
+	if v := ctx.Value(EnvKey); v != nil {
+		return v.(map[string]string)
+	}
+
+	return nil
+}
+
 func RunInteractive(ctx context.Context, command string, cwd string, args ...string) (string, error) {
 	logger := log.With().
 		Str("command", command).
@@ -23,13 +81,23 @@ func RunInteractive(ctx context.Context, command string, cwd string, args ...str
 	cmd := exec.CommandContext(ctx, command, args...)
 	cmd.Dir = cwd
 	cmd.Env = os.Environ()
+	for k, v := range EnvFromContext(ctx) {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+	}
 
 	//Currently keep the buffer in memory. We might want to change this to a file if the output is too large
 	stdOut := new(bytes.Buffer)
 
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = stdOut
-	cmd.Stdout = stdOut
+	var out io.Writer = stdOut
+	if w := OutputWriterFromContext(ctx); w != nil {
+		out = io.MultiWriter(stdOut, w)
+	}
+
+	if !NonInteractiveFromContext(ctx) {
+		cmd.Stdin = os.Stdin
+	}
+	cmd.Stderr = out
+	cmd.Stdout = out
 
 	err := cmd.Start()
 	if err != nil {
@@ -10,6 +10,23 @@ import (
 	"os/exec"
 )
 
+const TerraformBinaryKey = "terraform-binary"
+
+// ContextWithTerraformBinary returns a context in which terraform commands are run with the given binary, for example
+// `tofu` or the path to an installed terraform version
+func ContextWithTerraformBinary(ctx context.Context, binary string) context.Context {
+	return context.WithValue(ctx, TerraformBinaryKey, binary)
+}
+
+// TerraformBinaryFromContext returns the binary terraform commands are run with. Defaults to `terraform`
+func TerraformBinaryFromContext(ctx context.Context) string {
+	if v := ctx.Value(TerraformBinaryKey); v != nil {
+		return v.(string)
+	}
+
+	return "terraform"
+}
+
 // RunTerraform will execute a terraform command with the given arguments in the given directory.
 func RunTerraform(ctx context.Context, cwd string, args ...string) (string, error) {
 	if _, err := os.Stat(cwd); err != nil {
@@ -18,7 +35,7 @@ func RunTerraform(ctx context.Context, cwd string, args ...string) (string, erro
 		}
 	}
 
-	execPath, err := exec.LookPath("terraform")
+	execPath, err := exec.LookPath(TerraformBinaryFromContext(ctx))
 	if err != nil {
 		return "", err
 	}
@@ -29,7 +46,8 @@ func RunTerraform(ctx context.Context, cwd string, args ...string) (string, erro
 func GetTerraformOutputs(ctx context.Context, path string) (cty.Value, error) {
 	var data json.SimpleJSONValue
 
-	output, err := RunTerraform(ctx, path, "output", "-json")
+	// The outputs are only used internally, so they should not be streamed
+	output, err := RunTerraform(ContextWithOutputWriter(ctx, nil), path, "output", "-json")
 	if err != nil {
 		log.Error().Err(err).Msgf("failed to get terraform output: %s", err.Error())
 		return cty.NilVal, err